@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"soulprint-backend/safety"
+)
+
+// Check is the outcome of one deterministic check on an output.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// runChecks scores an output against a case's expectations and the phrases that
+// are forbidden everywhere.
+func runChecks(output string, expect expectations, forbidden []string) []Check {
+	var checks []Check
+	lower := strings.ToLower(output)
+
+	if expect.MinWords > 0 || expect.MaxWords > 0 {
+		words := len(strings.Fields(output))
+		check := Check{Name: "length", Passed: true, Detail: fmt.Sprintf("%d words", words)}
+		if words < expect.MinWords || (expect.MaxWords > 0 && words > expect.MaxWords) {
+			check.Passed = false
+			check.Detail = fmt.Sprintf("%d words, want %d to %d", words, expect.MinWords, expect.MaxWords)
+		}
+		checks = append(checks, check)
+	}
+
+	if len(expect.Keywords) > 0 {
+		var missing []string
+		for _, keyword := range expect.Keywords {
+			if !containsAny(lower, strings.Split(keyword, "|")) {
+				missing = append(missing, keyword)
+			}
+		}
+		check := Check{Name: "keywords", Passed: len(missing) == 0}
+		if !check.Passed {
+			check.Detail = "missing " + strings.Join(missing, ", ")
+		}
+		checks = append(checks, check)
+	}
+
+	var found []string
+	for _, phrase := range append(append([]string{}, forbidden...), expect.Forbidden...) {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			found = append(found, phrase)
+		}
+	}
+	check := Check{Name: "forbidden", Passed: len(found) == 0}
+	if !check.Passed {
+		check.Detail = "contains " + strings.Join(found, ", ")
+	}
+	checks = append(checks, check)
+
+	if expect.JSON {
+		check := Check{Name: "json", Passed: true}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(jsonObject(output)), &v); err != nil {
+			check.Passed = false
+			check.Detail = err.Error()
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// safetyCheck compares an assessed safety level with the expected one.
+func safetyCheck(got safety.Level, want string) Check {
+	if want == "" {
+		want = safety.LevelNone.String()
+	}
+	check := Check{Name: "safety", Passed: got.String() == want, Detail: got.String()}
+	if !check.Passed {
+		check.Detail = fmt.Sprintf("level %s, want %s", got, want)
+	}
+	return check
+}
+
+// jsonObject returns the outermost {...} in a reply, since models like to wrap JSON
+// in prose or code fences.
+func jsonObject(reply string) string {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
+
+func containsAny(s string, terms []string) bool {
+	for _, term := range terms {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" && strings.Contains(s, term) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+// Command eval runs a fixture set of journal entries through one or more
+// provider/model/style variants, scores the outputs with deterministic checks and,
+// optionally, a model acting as judge, and writes a comparison report:
+//
+//	go run ./cmd/eval
+//	go run ./cmd/eval -only default,local-llama3 -judge openai:gpt-4o
+//	go run ./cmd/eval -out new.json -baseline old.json
+//
+// To compare prompt changes, run the eval before and after the change and pass the
+// earlier JSON report as -baseline. Nothing is read from or written to MongoDB.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/safety"
+	"soulprint-backend/utils"
+)
+
+const defaultCriteria = "The reflection responds to what the entry actually says, is empathetic without being saccharine, offers a useful perspective or question, and does not give medical or legal advice."
+
+// fixtures is the file given by -cases.
+type fixtures struct {
+	// Forbidden phrases fail every case whose output contains them.
+	Forbidden []string   `json:"forbidden"`
+	Cases     []evalCase `json:"cases"`
+}
+
+type evalCase struct {
+	Name     string       `json:"name"`
+	Task     string       `json:"task,omitempty"` // "reflection" (default) or "risk"
+	Type     string       `json:"type,omitempty"` // reflection type, defaults to "insight"
+	Content  string       `json:"content"`
+	Criteria string       `json:"criteria,omitempty"` // for the judge, defaults to defaultCriteria
+	Expect   expectations `json:"expect"`
+}
+
+type expectations struct {
+	MinWords  int      `json:"min_words,omitempty"`
+	MaxWords  int      `json:"max_words,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"` // each must appear; "a|b" accepts either
+	Forbidden []string `json:"forbidden,omitempty"`
+	JSON      bool     `json:"json,omitempty"`   // output must contain a JSON object; implied for risk cases
+	Safety    string   `json:"safety,omitempty"` // expected safety level, defaults to "none"
+}
+
+// variant is one provider/model/style combination from the -variants file. An
+// empty provider uses the configured one.
+type variant struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Tone     string `json:"tone,omitempty"`
+	Length   string `json:"length,omitempty"`
+	// MinWords and MaxWords replace the cases' bounds, for styles that change length.
+	MinWords int `json:"min_words,omitempty"`
+	MaxWords int `json:"max_words,omitempty"`
+}
+
+func main() {
+	casesPath := flag.String("cases", "testdata/eval/cases.json", "fixture file")
+	variantsPath := flag.String("variants", "testdata/eval/variants.json", "variants file")
+	only := flag.String("only", "", "comma-separated variant names to run (default: all)")
+	judge := flag.String("judge", "", "provider:model to grade reflections with, e.g. openai:gpt-4o (default: no judge)")
+	out := flag.String("out", "eval-report.json", "where to write the JSON report")
+	markdown := flag.String("markdown", "", "where to write the Markdown report (default: stdout)")
+	baseline := flag.String("baseline", "", "earlier JSON report to compare against")
+	failUnder := flag.Float64("fail-under", 0, "exit 1 if any variant passes less than this fraction of cases")
+	flag.Parse()
+
+	config.LoadConfig()
+
+	var f fixtures
+	if err := readJSON(*casesPath, &f); err != nil {
+		log.Fatal(err)
+	}
+	var v struct {
+		Variants []variant `json:"variants"`
+	}
+	if err := readJSON(*variantsPath, &v); err != nil {
+		log.Fatal(err)
+	}
+	variants, err := selectVariants(v.Variants, *only)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var judgeClient *utils.OpenAIClient
+	if *judge != "" {
+		if judgeClient, err = utils.NewClient(parseProvider(*judge)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	report := Report{
+		GeneratedAt:   time.Now().UTC(),
+		PromptVersion: utils.PromptVersion,
+		Judge:         *judge,
+	}
+	for _, variant := range variants {
+		log.Printf("eval: running %s (%d cases)", variant.Name, len(f.Cases))
+		result, err := runVariant(variant, f, judgeClient)
+		if err != nil {
+			log.Fatalf("variant %s: %v", variant.Name, err)
+		}
+		report.Variants = append(report.Variants, result)
+	}
+
+	var previous *Report
+	if *baseline != "" {
+		previous = &Report{}
+		if err := readJSON(*baseline, previous); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	md := renderMarkdown(report, previous)
+	if *markdown == "" {
+		fmt.Print(md)
+	} else if err := os.WriteFile(*markdown, []byte(md), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, result := range report.Variants {
+		if result.Summary.PassRate() < *failUnder {
+			log.Printf("eval: %s passed %.0f%% of cases, below %.0f%%", result.Name, 100*result.Summary.PassRate(), 100**failUnder)
+			os.Exit(1)
+		}
+	}
+}
+
+func runVariant(v variant, f fixtures, judge *utils.OpenAIClient) (VariantResult, error) {
+	provider := utils.DefaultProvider()
+	if v.Provider != "" {
+		provider = utils.Provider{Name: v.Provider, Model: v.Model, URL: v.URL}
+	}
+	base, err := utils.NewClient(provider)
+	if err != nil {
+		return VariantResult{}, err
+	}
+
+	result := VariantResult{
+		Name:     v.Name,
+		Provider: provider.Name,
+		Model:    base.Model(),
+		Tone:     v.Tone,
+		Length:   v.Length,
+	}
+	lexicon := safety.NewLexiconClassifier()
+	for _, c := range f.Cases {
+		var usage utils.Usage
+		client := base.WithUsage(func(u utils.Usage) {
+			usage.PromptTokens += u.PromptTokens
+			usage.CompletionTokens += u.CompletionTokens
+		})
+
+		cr := CaseResult{Case: c.Name}
+		expect := c.Expect
+		started := time.Now()
+		switch c.Task {
+		case "", "reflection":
+			cr.Output, cr.Support, cr.Checks, err = runReflection(client, lexicon, c, v)
+			if !cr.Support && (v.MinWords > 0 || v.MaxWords > 0) {
+				expect.MinWords, expect.MaxWords = v.MinWords, v.MaxWords
+			}
+		case "risk":
+			cr.Output, cr.Checks, err = runRisk(client, c)
+			expect.JSON = true
+		default:
+			return VariantResult{}, fmt.Errorf("case %s: unknown task %q", c.Name, c.Task)
+		}
+		cr.LatencyMS = time.Since(started).Milliseconds()
+		cr.PromptTokens, cr.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+
+		if err != nil {
+			cr.Error = err.Error()
+		} else {
+			cr.Checks = append(cr.Checks, runChecks(cr.Output, expect, f.Forbidden)...)
+			if judge != nil && !cr.Support && c.Task != "risk" {
+				cr.JudgeScore, cr.JudgeReason = runJudge(judge, c, cr.Output)
+			}
+		}
+		result.Cases = append(result.Cases, cr)
+	}
+	result.Summary = summarize(result.Cases)
+	return result, nil
+}
+
+// runReflection screens the entry the way the server does, returning the
+// supportive response for flagged entries and a generated reflection otherwise.
+func runReflection(client *utils.OpenAIClient, lexicon *safety.LexiconClassifier, c evalCase, v variant) (output string, support bool, checks []Check, err error) {
+	assessment, err := lexicon.Classify(c.Content)
+	if err != nil {
+		return "", false, nil, err
+	}
+	checks = []Check{safetyCheck(assessment.Level, c.Expect.Safety)}
+	if assessment.Flagged() {
+		return safety.SupportiveResponse(assessment, config.AppConfig.CrisisResources), true, checks, nil
+	}
+
+	reflectionType := c.Type
+	if reflectionType == "" {
+		reflectionType = "insight"
+	}
+	output, err = client.GenerateReflection(c.Content, reflectionType, utils.ReflectionStyle{Tone: v.Tone, Length: v.Length})
+	return output, false, checks, err
+}
+
+// runRisk asks the model to classify the entry and scores its level.
+func runRisk(client *utils.OpenAIClient, c evalCase) (string, []Check, error) {
+	output, err := client.ClassifyRisk(c.Content)
+	if err != nil {
+		return "", nil, err
+	}
+	assessment, err := safety.NewModelClassifier(func(string) (string, error) { return output, nil }).Classify(c.Content)
+	if err != nil {
+		return output, []Check{{Name: "safety", Detail: err.Error()}}, nil
+	}
+	return output, []Check{safetyCheck(assessment.Level, c.Expect.Safety)}, nil
+}
+
+func runJudge(judge *utils.OpenAIClient, c evalCase, output string) (*float64, string) {
+	criteria := c.Criteria
+	if criteria == "" {
+		criteria = defaultCriteria
+	}
+	reply, err := judge.JudgeReflection(c.Content, output, criteria)
+	if err != nil {
+		return nil, "judge failed: " + err.Error()
+	}
+	var verdict struct {
+		Score  float64 `json:"score"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject(reply)), &verdict); err != nil || verdict.Score < 1 || verdict.Score > 5 {
+		return nil, fmt.Sprintf("judge returned no usable score: %q", reply)
+	}
+	return &verdict.Score, verdict.Reason
+}
+
+func selectVariants(all []variant, only string) ([]variant, error) {
+	if only == "" {
+		return all, nil
+	}
+	byName := make(map[string]variant, len(all))
+	for _, v := range all {
+		byName[v.Name] = v
+	}
+	var selected []variant
+	for _, name := range strings.Split(only, ",") {
+		v, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown variant %q", name)
+		}
+		selected = append(selected, v)
+	}
+	return selected, nil
+}
+
+// parseProvider parses "provider:model", where the model is optional.
+func parseProvider(spec string) utils.Provider {
+	name, model, _ := strings.Cut(spec, ":")
+	return utils.Provider{Name: name, Model: model}
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return nil
+}
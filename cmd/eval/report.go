@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is the JSON report of one eval run. It doubles as the -baseline input.
+type Report struct {
+	GeneratedAt   time.Time       `json:"generated_at"`
+	PromptVersion string          `json:"prompt_version"`
+	Judge         string          `json:"judge,omitempty"`
+	Variants      []VariantResult `json:"variants"`
+}
+
+type VariantResult struct {
+	Name     string       `json:"name"`
+	Provider string       `json:"provider"`
+	Model    string       `json:"model"`
+	Tone     string       `json:"tone,omitempty"`
+	Length   string       `json:"length,omitempty"`
+	Summary  Summary      `json:"summary"`
+	Cases    []CaseResult `json:"cases"`
+}
+
+type CaseResult struct {
+	Case             string   `json:"case"`
+	Output           string   `json:"output"`
+	Support          bool     `json:"support,omitempty"` // the supportive response replaced the reflection
+	Error            string   `json:"error,omitempty"`
+	Checks           []Check  `json:"checks"`
+	JudgeScore       *float64 `json:"judge_score,omitempty"`
+	JudgeReason      string   `json:"judge_reason,omitempty"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	LatencyMS        int64    `json:"latency_ms"`
+}
+
+// Passed reports whether the case ran and every check passed.
+func (cr CaseResult) Passed() bool {
+	if cr.Error != "" {
+		return false
+	}
+	for _, check := range cr.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+type Summary struct {
+	Cases            int            `json:"cases"`
+	Passed           int            `json:"passed"`
+	Errors           int            `json:"errors"`
+	FailedChecks     map[string]int `json:"failed_checks,omitempty"`
+	AverageWords     float64        `json:"average_words"`
+	AverageJudge     *float64       `json:"average_judge,omitempty"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	AverageLatencyMS int64          `json:"average_latency_ms"`
+}
+
+func (s Summary) PassRate() float64 {
+	if s.Cases == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Cases)
+}
+
+func summarize(cases []CaseResult) Summary {
+	s := Summary{Cases: len(cases), FailedChecks: make(map[string]int)}
+	words, outputs, judged := 0, 0, 0
+	judgeTotal := 0.0
+	var latency int64
+	for _, cr := range cases {
+		if cr.Passed() {
+			s.Passed++
+		}
+		if cr.Error != "" {
+			s.Errors++
+		} else {
+			words += len(strings.Fields(cr.Output))
+			outputs++
+		}
+		for _, check := range cr.Checks {
+			if !check.Passed {
+				s.FailedChecks[check.Name]++
+			}
+		}
+		if cr.JudgeScore != nil {
+			judgeTotal += *cr.JudgeScore
+			judged++
+		}
+		s.PromptTokens += cr.PromptTokens
+		s.CompletionTokens += cr.CompletionTokens
+		latency += cr.LatencyMS
+	}
+	if outputs > 0 {
+		s.AverageWords = float64(words) / float64(outputs)
+	}
+	if judged > 0 {
+		average := judgeTotal / float64(judged)
+		s.AverageJudge = &average
+	}
+	if len(cases) > 0 {
+		s.AverageLatencyMS = latency / int64(len(cases))
+	}
+	return s
+}
+
+// renderMarkdown writes the comparison report: a summary row per variant, a case by
+// variant matrix, the failures, and the change from the baseline if there is one.
+func renderMarkdown(report Report, baseline *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Reflection eval\n\nPrompt version %s, %s", report.PromptVersion, report.GeneratedAt.Format(time.RFC3339))
+	if report.Judge != "" {
+		fmt.Fprintf(&b, ", judged by %s", report.Judge)
+	}
+	b.WriteString("\n\n## Summary\n\n")
+	b.WriteString("| Variant | Model | Style | Passed | Errors | Avg words | Judge | Tokens | Avg latency |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+	for _, v := range report.Variants {
+		s := v.Summary
+		fmt.Fprintf(&b, "| %s | %s | %s | %d/%d (%.0f%%) | %d | %.0f | %s | %d | %dms |\n",
+			v.Name, v.Model, style(v), s.Passed, s.Cases, 100*s.PassRate(), s.Errors, s.AverageWords,
+			score(s.AverageJudge), s.PromptTokens+s.CompletionTokens, s.AverageLatencyMS)
+	}
+
+	if len(report.Variants) > 0 {
+		b.WriteString("\n## Cases\n\n| Case |")
+		for _, v := range report.Variants {
+			fmt.Fprintf(&b, " %s |", v.Name)
+		}
+		b.WriteString("\n|---|" + strings.Repeat("---|", len(report.Variants)) + "\n")
+		for i, cr := range report.Variants[0].Cases {
+			fmt.Fprintf(&b, "| %s |", cr.Case)
+			for _, v := range report.Variants {
+				fmt.Fprintf(&b, " %s |", cell(v.Cases[i]))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	var failures []string
+	for _, v := range report.Variants {
+		for _, cr := range v.Cases {
+			if cr.Error != "" {
+				failures = append(failures, fmt.Sprintf("| %s | %s | error | %s |", v.Name, cr.Case, escape(cr.Error)))
+			}
+			for _, check := range cr.Checks {
+				if !check.Passed {
+					failures = append(failures, fmt.Sprintf("| %s | %s | %s | %s |", v.Name, cr.Case, check.Name, escape(check.Detail)))
+				}
+			}
+		}
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n## Failures\n\n| Variant | Case | Check | Detail |\n|---|---|---|---|\n")
+		b.WriteString(strings.Join(failures, "\n") + "\n")
+	}
+
+	if baseline != nil {
+		renderBaseline(&b, report, *baseline)
+	}
+	return b.String()
+}
+
+// renderBaseline compares variants with the same name in both reports.
+func renderBaseline(b *strings.Builder, report, baseline Report) {
+	previous := make(map[string]VariantResult, len(baseline.Variants))
+	for _, v := range baseline.Variants {
+		previous[v.Name] = v
+	}
+
+	fmt.Fprintf(b, "\n## Compared with baseline\n\nBaseline: prompt version %s, %s\n\n", baseline.PromptVersion, baseline.GeneratedAt.Format(time.RFC3339))
+	b.WriteString("| Variant | Pass rate | Judge | Tokens | Newly failing | Newly passing |\n|---|---|---|---|---|---|\n")
+	var names []string
+	for _, v := range report.Variants {
+		old, ok := previous[v.Name]
+		if !ok {
+			fmt.Fprintf(b, "| %s | not in baseline | | | | |\n", v.Name)
+			continue
+		}
+		oldPassed := make(map[string]bool, len(old.Cases))
+		for _, cr := range old.Cases {
+			oldPassed[cr.Case] = cr.Passed()
+		}
+		var newlyFailing, newlyPassing []string
+		for _, cr := range v.Cases {
+			was, ok := oldPassed[cr.Case]
+			switch {
+			case !ok:
+			case was && !cr.Passed():
+				newlyFailing = append(newlyFailing, cr.Case)
+			case !was && cr.Passed():
+				newlyPassing = append(newlyPassing, cr.Case)
+			}
+		}
+		s, o := v.Summary, old.Summary
+		fmt.Fprintf(b, "| %s | %.0f%% (%+.0f) | %s (was %s) | %d (%+d) | %s | %s |\n",
+			v.Name, 100*s.PassRate(), 100*(s.PassRate()-o.PassRate()), score(s.AverageJudge), score(o.AverageJudge),
+			s.PromptTokens+s.CompletionTokens, s.PromptTokens+s.CompletionTokens-o.PromptTokens-o.CompletionTokens,
+			strings.Join(newlyFailing, ", "), strings.Join(newlyPassing, ", "))
+		delete(previous, v.Name)
+	}
+	for name := range previous {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "| %s | only in baseline | | | | |\n", name)
+	}
+}
+
+func style(v VariantResult) string {
+	parts := []string{}
+	if v.Tone != "" {
+		parts = append(parts, v.Tone)
+	}
+	if v.Length != "" {
+		parts = append(parts, v.Length)
+	}
+	if len(parts) == 0 {
+		return "default"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func cell(cr CaseResult) string {
+	mark := "pass"
+	if cr.Error != "" {
+		mark = "error"
+	} else if !cr.Passed() {
+		mark = "FAIL"
+	}
+	if cr.Support {
+		mark += " (support)"
+	}
+	if cr.JudgeScore != nil {
+		mark += fmt.Sprintf(" %.1f", *cr.JudgeScore)
+	}
+	return mark
+}
+
+func score(s *float64) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *s)
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,53 @@
+// Command fakellm serves the fakellm stand-in for OpenAI and Ollama, for local
+// development and end-to-end tests without a model:
+//
+//	go run ./cmd/fakellm -addr :8081
+//	go run ./cmd/fakellm -rules testdata/fakellm_rules.json -latency 500ms
+//
+// Point the backend at it with OPENAI_BASE_URL=http://localhost:8081/v1, or with
+// AI_PROVIDER=local and LOCAL_MODEL_URL=http://localhost:8081.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"soulprint-backend/fakellm"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	rulesPath := flag.String("rules", "", "JSON file with scripted responses and faults")
+	model := flag.String("model", "fake", "model name reported when a request names none")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	errorRate := flag.Float64("error-rate", 0, "fraction of generated responses that fail with 500")
+	dimensions := flag.Int("dimensions", 64, "length of embedding vectors")
+	flag.Parse()
+
+	opts := fakellm.Options{
+		Model:      *model,
+		Latency:    *latency,
+		ErrorRate:  *errorRate,
+		Dimensions: *dimensions,
+	}
+	if *rulesPath != "" {
+		rules, err := fakellm.LoadRules(*rulesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Rules = rules
+		log.Printf("fakellm: loaded %d rule(s) from %s", len(rules), *rulesPath)
+	}
+
+	server := fakellm.New(opts)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		server.ServeHTTP(w, r)
+		log.Printf("fakellm: %s %s (%s)", r.Method, r.URL.Path, time.Since(started).Round(time.Millisecond))
+	})
+
+	log.Printf("fakellm: listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -9,7 +9,10 @@ import (
 
 	"soulprint-backend/config"
 	"soulprint-backend/controllers"
+	"soulprint-backend/encryption"
+	"soulprint-backend/notifications"
 	"soulprint-backend/routes"
+	"soulprint-backend/scheduler"
 	"soulprint-backend/services"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -27,24 +30,87 @@ func main() {
 	}
 	defer mongoClient.Disconnect(context.Background())
 
+	// Set up encryption at rest
+	encryptor, err := newEncryptor(mongoClient)
+	if err != nil {
+		log.Fatal("Failed to set up encryption:", err)
+	}
+
 	// Initialize services
-	journalService := services.NewJournalService(mongoClient)
-	aiService := services.NewAIService(mongoClient, journalService)
+	webhookService := services.NewWebhookService(mongoClient, encryptor)
+	settingsService := services.NewSettingsService(mongoClient, encryptor)
+	usageService := services.NewUsageService(mongoClient)
+	templateService := services.NewTemplateService(mongoClient)
+	journalService := services.NewJournalService(mongoClient, webhookService, encryptor, settingsService, templateService)
+	aiService := services.NewAIService(mongoClient, journalService, webhookService, encryptor, usageService)
+	batchService := services.NewReflectionBatchService(mongoClient, aiService)
+	digestService := services.NewDigestService(mongoClient, journalService, aiService)
+	reportService := services.NewReportService(mongoClient, journalService, aiService, digestService)
+	reminderService := services.NewReminderService(mongoClient, journalService, newDispatcher())
+	topicService := services.NewTopicService(mongoClient, journalService, encryptor)
+	promptService := services.NewPromptService(journalService, topicService, templateService)
+	jobScheduler := scheduler.New(mongoClient)
+
+	// Create indexes before serving: revision numbers, feedback, settings, key backups
+	// and year reviews rely on unique indexes. The index-maintenance job keeps them up
+	// to date afterwards.
+	indexes := []func(context.Context) error{
+		journalService.EnsureIndexes,
+		aiService.EnsureIndexes,
+		batchService.EnsureIndexes,
+		digestService.EnsureIndexes,
+		reportService.EnsureIndexes,
+		reminderService.EnsureIndexes,
+		webhookService.EnsureIndexes,
+		topicService.EnsureIndexes,
+		templateService.EnsureIndexes,
+		settingsService.EnsureIndexes,
+		usageService.EnsureIndexes,
+		encryptor.EnsureIndexes,
+		jobScheduler.EnsureIndexes,
+	}
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 2*time.Minute)
+	err = ensureIndexes(indexCtx, indexes)
+	cancelIndexes()
+	if err != nil {
+		log.Fatal("Failed to create indexes:", err)
+	}
 
 	// Initialize controllers
 	journalController := controllers.NewJournalController(journalService)
-	reflectionController := controllers.NewReflectionController(aiService)
+	reflectionController := controllers.NewReflectionController(aiService, batchService)
+	digestController := controllers.NewDigestController(digestService)
+	reportController := controllers.NewReportController(reportService)
+	adminController := controllers.NewAdminController(jobScheduler)
+	reminderController := controllers.NewReminderController(reminderService)
+	webhookController := controllers.NewWebhookController(webhookService)
+	topicController := controllers.NewTopicController(topicService)
+	promptController := controllers.NewPromptController(promptService, templateService)
+	settingsController := controllers.NewSettingsController(settingsService)
+	usageController := controllers.NewUsageController(usageService)
+
+	// Register and start background jobs
+	registerJobs(jobScheduler, indexes, journalService, batchService, digestService, reminderService, webhookService, topicService)
+	if config.AppConfig.SchedulerEnabled {
+		jobScheduler.Start(context.Background())
+	}
 
 	// Setup routes
-	router := routes.NewRouter(journalController, reflectionController)
+	router := routes.NewRouter(journalController, reflectionController, digestController, reportController, adminController, reminderController, webhookController, settingsController, usageController, topicController, promptController)
 
 	// Start server
 	port := config.AppConfig.Port
 	fmt.Printf("🌟 Soulprint Backend starting on port %s\n", port)
 	fmt.Printf("📖 MongoDB: %s\n", config.AppConfig.MongoDatabase)
-	if config.AppConfig.UseLocalModel {
-		fmt.Printf("🤖 AI Model: Local Llama3 (%s)\n", config.AppConfig.LocalModelURL)
-	} else {
+	if encryptor != nil {
+		fmt.Println("🔒 Journal content encrypted at rest")
+	}
+	switch config.AppConfig.AIProvider {
+	case "local":
+		fmt.Printf("🤖 AI Model: Local %s (%s)\n", config.AppConfig.LocalModelName, config.AppConfig.LocalModelURL)
+	case "fake":
+		fmt.Println("🤖 AI Model: fake provider (deterministic templates, no network)")
+	default:
 		fmt.Printf("🤖 AI Model: %s\n", config.AppConfig.OpenAIModel)
 	}
 	fmt.Println("✨ Available endpoints:")
@@ -54,15 +120,174 @@ func main() {
 	fmt.Println("   GET  /api/v1/entries")
 	fmt.Println("   GET  /api/v1/entries/{id}")
 	fmt.Println("   PUT  /api/v1/entries/{id}")
+	fmt.Println("   PATCH /api/v1/entries/{id}")
 	fmt.Println("   DELETE /api/v1/entries/{id}")
+	fmt.Println("   GET  /api/v1/entries/{id}/revisions")
+	fmt.Println("   GET  /api/v1/entries/{id}/revisions/{revision}")
+	fmt.Println("   POST /api/v1/entries/{id}/revisions/{revision}/restore")
+	fmt.Println("   GET  /api/v1/entries/{id}/diff")
+	fmt.Println("   GET  /api/v1/trash")
+	fmt.Println("   POST /api/v1/trash/{id}/restore")
+	fmt.Println("   DELETE /api/v1/trash/{id}")
 	fmt.Println("   POST /api/v1/reflect")
+	fmt.Println("   POST /api/v1/reflect/batch")
+	fmt.Println("   GET  /api/v1/reflect/batch/{id}")
 	fmt.Println("   GET  /api/v1/insights")
 	fmt.Println("   GET  /api/v1/reflections")
 	fmt.Println("   GET  /api/v1/entries/{id}/reflections")
+	fmt.Println("   POST /api/v1/reflections/{id}/regenerate")
+	fmt.Println("   GET  /api/v1/reflections/{id}/versions")
+	fmt.Println("   POST /api/v1/reflections/{id}/feedback")
+	fmt.Println("   GET  /api/v1/reflections/{id}/feedback")
+	fmt.Println("   POST /api/v1/digests")
+	fmt.Println("   GET  /api/v1/digests")
+	fmt.Println("   GET  /api/v1/reports/year/{year}")
+	fmt.Println("   GET  /api/v1/topics")
+	fmt.Println("   POST /api/v1/topics/refresh")
+	fmt.Println("   GET  /api/v1/topics/{id}/entries")
+	fmt.Println("   GET  /api/v1/prompts")
+	fmt.Println("   GET  /api/v1/prompts/suggest")
+	fmt.Println("   GET  /api/v1/templates")
+	fmt.Println("   POST /api/v1/templates")
+	fmt.Println("   GET  /api/v1/templates/{id}")
+	fmt.Println("   PUT  /api/v1/templates/{id}")
+	fmt.Println("   DELETE /api/v1/templates/{id}")
+	fmt.Println("   POST /api/v1/reminders")
+	fmt.Println("   GET  /api/v1/reminders")
+	fmt.Println("   PUT  /api/v1/reminders/{id}")
+	fmt.Println("   DELETE /api/v1/reminders/{id}")
+	fmt.Println("   POST /api/v1/reminders/{id}/test")
+	fmt.Println("   POST /api/v1/webhooks")
+	fmt.Println("   GET  /api/v1/webhooks")
+	fmt.Println("   PUT  /api/v1/webhooks/{id}")
+	fmt.Println("   DELETE /api/v1/webhooks/{id}")
+	fmt.Println("   GET  /api/v1/webhooks/{id}/deliveries")
+	fmt.Println("   POST /api/v1/webhooks/{id}/ping")
+	fmt.Println("   GET  /api/v1/settings")
+	fmt.Println("   PUT  /api/v1/settings")
+	fmt.Println("   GET  /api/v1/keys/backups")
+	fmt.Println("   GET  /api/v1/keys/backups/{keyId}")
+	fmt.Println("   PUT  /api/v1/keys/backups/{keyId}")
+	fmt.Println("   DELETE /api/v1/keys/backups/{keyId}")
+	fmt.Println("   GET  /api/v1/usage")
+	fmt.Println("   GET  /api/v1/admin/jobs")
+	fmt.Println("   GET  /api/v1/admin/jobs/{name}/runs")
+	fmt.Println("   POST /api/v1/admin/jobs/{name}/run")
+	fmt.Println("   GET  /api/v1/admin/usage")
+	fmt.Println("   GET  /api/v1/admin/feedback")
 
 	log.Fatal(http.ListenAndServe(":"+port, router))
 }
 
+func registerJobs(s *scheduler.Scheduler, indexes []func(context.Context) error, journalService *services.JournalService, batchService *services.ReflectionBatchService, digestService *services.DigestService, reminderService *services.ReminderService, webhookService *services.WebhookService, topicService *services.TopicService) {
+	jobs := []struct {
+		name    string
+		spec    string
+		timeout time.Duration
+		run     scheduler.JobFunc
+	}{
+		{"digests", config.AppConfig.DigestSchedule, 30 * time.Minute, func(ctx context.Context) error {
+			created, err := digestService.GenerateDueDigests(ctx)
+			if created > 0 {
+				log.Printf("digest: generated %d digest(s)", created)
+			}
+			return err
+		}},
+		{"reminders", config.AppConfig.ReminderSchedule, 4 * time.Minute, func(ctx context.Context) error {
+			_, err := reminderService.SendDueReminders(ctx, time.Now())
+			return err
+		}},
+		{"trash-purge", config.AppConfig.TrashPurgeSchedule, 15 * time.Minute, func(ctx context.Context) error {
+			purged, err := journalService.PurgeExpired(ctx, config.AppConfig.TrashRetention)
+			if purged > 0 {
+				log.Printf("trash: purged %d entries", purged)
+			}
+			return err
+		}},
+		{"keyword-backfill", config.AppConfig.KeywordSchedule, 10 * time.Minute, func(ctx context.Context) error {
+			updated, err := journalService.BackfillKeywords(ctx, 500)
+			if updated > 0 {
+				log.Printf("keywords: extracted keywords for %d entries", updated)
+			}
+			return err
+		}},
+		{"topics", config.AppConfig.TopicSchedule, 30 * time.Minute, func(ctx context.Context) error {
+			refreshed, err := topicService.RefreshAllTopics(ctx)
+			if refreshed > 0 {
+				log.Printf("topics: refreshed topics for %d user(s)", refreshed)
+			}
+			return err
+		}},
+		{"webhook-redelivery", config.AppConfig.WebhookResumeSchedule, 5 * time.Minute, func(ctx context.Context) error {
+			_, err := webhookService.ResumeStaleDeliveries(ctx)
+			return err
+		}},
+		{"reflection-batches", config.AppConfig.ReflectBatchResumeSchedule, 5 * time.Minute, func(ctx context.Context) error {
+			resumed, err := batchService.ResumeStaleBatches(ctx)
+			if resumed > 0 {
+				log.Printf("batch: resumed %d reflection batch(es)", resumed)
+			}
+			return err
+		}},
+		{"index-maintenance", config.AppConfig.IndexSchedule, 10 * time.Minute, func(ctx context.Context) error {
+			return ensureIndexes(ctx, indexes)
+		}},
+		{"job-history-cleanup", config.AppConfig.JobHistorySchedule, 5 * time.Minute, func(ctx context.Context) error {
+			_, err := s.PruneHistory(config.AppConfig.JobHistoryRetention)
+			return err
+		}},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job.name, job.spec, job.timeout, job.run); err != nil {
+			log.Fatal("Failed to register job:", err)
+		}
+	}
+}
+
+// ensureIndexes runs each index setup in turn and stops at the first error.
+func ensureIndexes(ctx context.Context, indexes []func(context.Context) error) error {
+	for _, ensure := range indexes {
+		if err := ensure(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newEncryptor returns the field encryptor, or nil when no master key is configured.
+func newEncryptor(client *mongo.Client) (*encryption.Encryptor, error) {
+	masterKey, err := encryption.LoadMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	if masterKey == nil {
+		log.Println("Warning: ENCRYPTION_MASTER_KEY / ENCRYPTION_KEY_FILE not set, journal content is stored unencrypted")
+		return nil, nil
+	}
+	return encryption.New(client, masterKey)
+}
+
+// newDispatcher registers the notification channels that are configured.
+func newDispatcher() *notifications.Dispatcher {
+	dispatcher := notifications.NewDispatcher()
+	dispatcher.Register(notifications.NoopChannel{})
+	dispatcher.Register(notifications.NewWebhookChannel(services.NewWebhookHTTPClient()))
+	if config.AppConfig.NotifyFilePath != "" {
+		dispatcher.Register(notifications.NewFileChannel(config.AppConfig.NotifyFilePath))
+	}
+	if config.AppConfig.SMTPHost != "" {
+		dispatcher.Register(notifications.NewSMTPChannel(
+			config.AppConfig.SMTPHost,
+			config.AppConfig.SMTPPort,
+			config.AppConfig.SMTPUsername,
+			config.AppConfig.SMTPPassword,
+			config.AppConfig.SMTPFrom,
+		))
+	}
+	return dispatcher
+}
+
 func connectMongoDB() (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
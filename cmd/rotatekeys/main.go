@@ -0,0 +1,170 @@
+// Command rotatekeys manages the keys used to encrypt journal content at rest.
+//
+//	rotatekeys -generate                  print a new master key
+//	rotatekeys                            rotate every user's data key and re-encrypt
+//	rotatekeys -user user123              the same for one user
+//	rotatekeys -reencrypt-only            re-encrypt with the current keys (e.g. after
+//	                                      enabling encryption on existing data)
+//	rotatekeys -rewrap-from old.key       re-wrap data keys after a master key change
+//
+// The master key is read from ENCRYPTION_MASTER_KEY or ENCRYPTION_KEY_FILE, as for
+// the server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/encryption"
+	"soulprint-backend/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	generate := flag.Bool("generate", false, "print a new random master key and exit")
+	userID := flag.String("user", "", "only process this user")
+	reencryptOnly := flag.Bool("reencrypt-only", false, "re-encrypt with the current data keys instead of rotating them")
+	rewrapFrom := flag.String("rewrap-from", "", "file with the previous master key; re-wraps data keys under the configured one")
+	flag.Parse()
+
+	if *generate {
+		key, err := encryption.GenerateMasterKey()
+		if err != nil {
+			log.Fatal("Failed to generate master key:", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	config.LoadConfig()
+	masterKey, err := encryption.LoadMasterKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if masterKey == nil {
+		log.Fatal("ENCRYPTION_MASTER_KEY or ENCRYPTION_KEY_FILE must be set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.AppConfig.MongoURI))
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	defer client.Disconnect(ctx)
+
+	encryptor, err := encryption.New(client, masterKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *rewrapFrom != "" {
+		oldKey, err := encryption.ReadMasterKeyFile(*rewrapFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		count, err := encryptor.Rewrap(ctx, oldKey)
+		if err != nil {
+			log.Fatalf("Re-wrapped %d data keys before failing: %v", count, err)
+		}
+		log.Printf("Re-wrapped %d data keys", count)
+		return
+	}
+
+	db := client.Database(config.AppConfig.MongoDatabase)
+	users := []string{*userID}
+	if *userID == "" {
+		if users, err = allUsers(ctx, db); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	failed := 0
+	for _, user := range users {
+		if err := processUser(ctx, db, encryptor, user, !*reencryptOnly); err != nil {
+			log.Printf("%s: %v", user, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d users failed", failed, len(users))
+	}
+	log.Printf("Processed %d users", len(users))
+}
+
+func processUser(ctx context.Context, db *mongo.Database, encryptor *encryption.Encryptor, userID string, rotate bool) error {
+	var keyID primitive.ObjectID
+	if rotate {
+		var err error
+		if keyID, err = encryptor.RotateUserKey(userID); err != nil {
+			return err
+		}
+		log.Printf("%s: new data key %s", userID, keyID.Hex())
+	}
+
+	skipped := 0
+	for _, name := range collectionNames() {
+		updated, skippedHere, err := encryptor.Reencrypt(ctx, db.Collection(name), userID, services.EncryptedFields[name])
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: %s: re-encrypted %d, skipped %d", userID, name, updated, skippedHere)
+		skipped += skippedHere
+	}
+	if skipped > 0 {
+		// Something changed underneath us; keep the old keys usable and ask for a rerun.
+		return fmt.Errorf("%d documents changed during re-encryption, run again", skipped)
+	}
+
+	if rotate {
+		// Retired keys still decrypt, so values a server wrote with a cached old key
+		// during the rotation stay readable; a later run moves them to the new key.
+		retired, err := encryptor.RetireKeys(ctx, userID, keyID)
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: retired %d old data keys", userID, retired)
+	}
+	return nil
+}
+
+func allUsers(ctx context.Context, db *mongo.Database) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, name := range collectionNames() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		values, err := db.Collection(name).Distinct(ctx, "user_id", bson.M{})
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list users in %s: %w", name, err)
+		}
+		for _, value := range values {
+			if user, ok := value.(string); ok && user != "" {
+				seen[user] = true
+			}
+		}
+	}
+
+	users := make([]string, 0, len(seen))
+	for user := range seen {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+func collectionNames() []string {
+	names := make([]string, 0, len(services.EncryptedFields))
+	for name := range services.EncryptedFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
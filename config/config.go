@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,11 +16,78 @@ type Config struct {
 	MongoDatabase  string
 	OpenAIAPIKey   string
 	OpenAIModel    string
+	OpenAIBaseURL  string
 	LocalModelURL  string
 	LocalModelName string
 	UseLocalModel  bool
+	AIProvider     string // "openai", "local" or "fake"
+
+	// Fake AI provider (AI_PROVIDER=fake)
+	FakeLLMLatency        time.Duration
+	FakeLLMErrorRate      float64
+	FakeLLMFailOperations []string
+
+	// Digest reflections
+	AIContextTokens int
+
+	// Keywords listed on reflections: "offline" (the entry's extracted keywords) or
+	// "model" (asked of the AI provider, offline on failure)
+	KeywordExtractor string
+
+	// Background jobs
+	SchedulerEnabled    bool
+	DigestSchedule      string
+	IndexSchedule       string
+	JobHistorySchedule  string
+	JobHistoryRetention time.Duration
+	ReminderSchedule    string
+	TrashPurgeSchedule  string
+	TrashRetention      time.Duration
+	KeywordSchedule     string
+	TopicSchedule       string
+
+	// Notifications
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPFrom       string
+	NotifyFilePath string
+	WebhookTimeout time.Duration
+
+	// Outbound webhooks
+	WebhookMaxAttempts    int
+	WebhookRetryBackoff   time.Duration
+	WebhookResumeSchedule string
+	// WebhookAllowPrivate lets webhooks reach loopback and private addresses, for
+	// local development only.
+	WebhookAllowPrivate bool
+
+	// Encryption at rest
+	EncryptionMasterKey string
+	EncryptionKeyFile   string
+
+	// AI usage metering; budgets are tokens per user, 0 means unlimited
+	AIDailyTokenBudget   int
+	AIMonthlyTokenBudget int
+	AIModelPrices        []string
+
+	// Batch reflections
+	ReflectBatchConcurrency    int
+	ReflectBatchRate           int // model calls per minute, across all batches
+	ReflectBatchResumeSchedule string
+
+	// Safety screening before reflections
+	SafetyModelClassifier bool
+	CrisisResources       []string
 }
 
+// defaultCrisisResources are shown with supportive responses when CRISIS_RESOURCES is
+// not set. Deployments serving a particular region should list local services.
+const defaultCrisisResources = "988 Suicide & Crisis Lifeline (US): call or text 988;" +
+	"Samaritans (UK & Ireland): call 116 123;" +
+	"Find a helpline in your country: https://findahelpline.com"
+
 var AppConfig *Config
 
 func LoadConfig() {
@@ -32,15 +102,80 @@ func LoadConfig() {
 		MongoDatabase:  getEnv("MONGODB_DATABASE", "soulprint"),
 		OpenAIAPIKey:   getEnv("OPENAI_API_KEY", ""),
 		OpenAIModel:    getEnv("OPENAI_MODEL", "gpt-3.5-turbo"),
+		OpenAIBaseURL:  getEnv("OPENAI_BASE_URL", ""),
 		LocalModelURL:  getEnv("LOCAL_MODEL_URL", "http://localhost:11434"),
 		LocalModelName: getEnv("LOCAL_MODEL_NAME", "llama3"),
 		UseLocalModel:  getEnv("USE_LOCAL_MODEL", "false") == "true",
+		AIProvider:     getEnv("AI_PROVIDER", ""),
+
+		FakeLLMLatency:        getEnvDuration("FAKE_LLM_LATENCY", 0),
+		FakeLLMErrorRate:      getEnvFloat("FAKE_LLM_ERROR_RATE", 0),
+		FakeLLMFailOperations: getEnvList("FAKE_LLM_FAIL_OPERATIONS", ""),
+
+		AIContextTokens: getEnvInt("AI_CONTEXT_TOKENS", 3000),
+
+		KeywordExtractor: getEnv("KEYWORD_EXTRACTOR", "offline"),
+
+		SchedulerEnabled:    getEnv("SCHEDULER_ENABLED", "true") == "true",
+		DigestSchedule:      getEnv("DIGEST_SCHEDULE", "15 * * * *"),
+		IndexSchedule:       getEnv("INDEX_SCHEDULE", "30 3 * * *"),
+		JobHistorySchedule:  getEnv("JOB_HISTORY_SCHEDULE", "0 4 * * *"),
+		JobHistoryRetention: getEnvDuration("JOB_HISTORY_RETENTION", 30*24*time.Hour),
+		ReminderSchedule:    getEnv("REMINDER_SCHEDULE", "*/5 * * * *"),
+		TrashPurgeSchedule:  getEnv("TRASH_PURGE_SCHEDULE", "0 2 * * *"),
+		TrashRetention:      getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		KeywordSchedule:     getEnv("KEYWORD_SCHEDULE", "*/30 * * * *"),
+		TopicSchedule:       getEnv("TOPIC_SCHEDULE", "0 5 * * *"),
+
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:       getEnv("SMTP_FROM", "Soulprint <no-reply@soulprint.local>"),
+		NotifyFilePath: getEnv("NOTIFY_FILE_PATH", ""),
+		WebhookTimeout: getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+
+		WebhookMaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryBackoff:   getEnvDuration("WEBHOOK_RETRY_BACKOFF", 2*time.Second),
+		WebhookResumeSchedule: getEnv("WEBHOOK_RESUME_SCHEDULE", "*/10 * * * *"),
+		WebhookAllowPrivate:   getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true",
+
+		EncryptionMasterKey: getEnv("ENCRYPTION_MASTER_KEY", ""),
+		EncryptionKeyFile:   getEnv("ENCRYPTION_KEY_FILE", ""),
+
+		AIDailyTokenBudget:   getEnvInt("AI_DAILY_TOKEN_BUDGET", 0),
+		AIMonthlyTokenBudget: getEnvInt("AI_MONTHLY_TOKEN_BUDGET", 0),
+		AIModelPrices:        getEnvList("AI_MODEL_PRICES", ""),
+
+		ReflectBatchConcurrency:    getEnvInt("REFLECT_BATCH_CONCURRENCY", 3),
+		ReflectBatchRate:           getEnvInt("REFLECT_BATCH_RATE", 30),
+		ReflectBatchResumeSchedule: getEnv("REFLECT_BATCH_RESUME_SCHEDULE", "*/10 * * * *"),
+
+		SafetyModelClassifier: getEnv("SAFETY_MODEL_CLASSIFIER", "false") == "true",
+		CrisisResources:       getEnvList("CRISIS_RESOURCES", defaultCrisisResources),
 	}
 
-	if AppConfig.UseLocalModel {
-		// Local model configuration loaded
-	} else if AppConfig.OpenAIAPIKey == "" {
-		log.Println("Warning: OPENAI_API_KEY not set and USE_LOCAL_MODEL is false")
+	// AI_PROVIDER takes precedence; USE_LOCAL_MODEL is kept for existing setups.
+	switch AppConfig.AIProvider {
+	case "":
+		AppConfig.AIProvider = "openai"
+		if AppConfig.UseLocalModel {
+			AppConfig.AIProvider = "local"
+		}
+	case "openai", "local", "fake":
+	default:
+		log.Printf("Warning: unknown AI_PROVIDER %q, using openai", AppConfig.AIProvider)
+		AppConfig.AIProvider = "openai"
+	}
+	AppConfig.UseLocalModel = AppConfig.AIProvider == "local"
+
+	if AppConfig.KeywordExtractor != "offline" && AppConfig.KeywordExtractor != "model" {
+		log.Printf("Warning: unknown KEYWORD_EXTRACTOR %q, using offline", AppConfig.KeywordExtractor)
+		AppConfig.KeywordExtractor = "offline"
+	}
+
+	if AppConfig.AIProvider == "openai" && AppConfig.OpenAIAPIKey == "" && AppConfig.OpenAIBaseURL == "" {
+		log.Println("Warning: OPENAI_API_KEY not set; AI features will fail. Set AI_PROVIDER=local or AI_PROVIDER=fake to run without it")
 	}
 }
 
@@ -50,3 +185,53 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s (%q), using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s (%q), using %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid value for %s (%q), using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvList splits a semicolon-separated value, dropping empty items.
+func getEnvList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
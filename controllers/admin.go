@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"soulprint-backend/scheduler"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminController struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewAdminController(scheduler *scheduler.Scheduler) *AdminController {
+	return &AdminController{
+		scheduler: scheduler,
+	}
+}
+
+// GET /admin/jobs
+func (ac *AdminController) GetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := ac.scheduler.Jobs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    jobs,
+	})
+}
+
+// GET /admin/jobs/{name}/runs?limit=20
+func (ac *AdminController) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !ac.scheduler.HasJob(name) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	limit := int64(20)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := ac.scheduler.History(name, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    runs,
+	})
+}
+
+// POST /admin/jobs/{name}/run
+func (ac *AdminController) RunJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := ac.scheduler.RunNow(name); err != nil {
+		switch err.Error() {
+		case "job not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case "job already running":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Job triggered",
+	})
+}
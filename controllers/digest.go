@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"soulprint-backend/models"
+	"soulprint-backend/services"
+)
+
+type DigestController struct {
+	digestService *services.DigestService
+}
+
+func NewDigestController(digestService *services.DigestService) *DigestController {
+	return &DigestController{
+		digestService: digestService,
+	}
+}
+
+// POST /digests
+func (dc *DigestController) GenerateDigest(w http.ResponseWriter, r *http.Request) {
+	var req models.DigestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Period == "" {
+		req.Period = "weekly"
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	digest, err := dc.digestService.GenerateDigest(userID, req)
+	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err.Error() == "no journal entries in digest window":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    digest,
+	})
+}
+
+// GET /digests?period=weekly
+func (dc *DigestController) GetDigests(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	digests, err := dc.digestService.GetDigests(userID, r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    digests,
+	})
+}
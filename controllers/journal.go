@@ -2,7 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"soulprint-backend/models"
 	"soulprint-backend/services"
@@ -28,21 +32,17 @@ func (jc *JournalController) CreateEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate required fields
-	if req.Title == "" || req.Content == "" {
-		http.Error(w, "Title and content are required", http.StatusBadRequest)
-		return
-	}
-
 	// For MVP, use hardcoded user ID
 	userID := "user123"
-	
+
+	// Required fields depend on the journal mode and are checked by the service.
 	entry, err := jc.journalService.CreateEntry(userID, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeEntryError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", entryETag(entry))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -84,11 +84,14 @@ func (jc *JournalController) GetEntry(w http.ResponseWriter, r *http.Request) {
 	
 	entry, err := jc.journalService.GetEntryByID(userID, entryID)
 	if err != nil {
-		if err.Error() == "journal entry not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeEntryError(w, err)
+		return
+	}
+
+	etag := entryETag(entry)
+	w.Header().Set("ETag", etag)
+	if etagListMatches(r.Header.Get("If-None-Match"), etag, false) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -115,25 +118,57 @@ func (jc *JournalController) UpdateEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate required fields
-	if req.Title == "" || req.Content == "" {
-		http.Error(w, "Title and content are required", http.StatusBadRequest)
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+	
+	expectedVersion, err := jc.expectedVersion(r, userID, entryID)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	entry, err := jc.journalService.UpdateEntry(userID, entryID, req, expectedVersion)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", entryETag(entry))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    entry,
+	})
+}
+
+// PATCH /entries/{id}
+// Content-Type application/merge-patch+json (or application/json) for a JSON Merge
+// Patch, application/json-patch+json for a JSON Patch.
+func (jc *JournalController) PatchEntry(w http.ResponseWriter, r *http.Request) {
+	entryID := mux.Vars(r)["id"]
+
+	patch, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil || len(patch) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// For MVP, use hardcoded user ID
 	userID := "user123"
-	
-	entry, err := jc.journalService.UpdateEntry(userID, entryID, req)
+
+	expectedVersion, err := jc.expectedVersion(r, userID, entryID)
 	if err != nil {
-		if err.Error() == "journal entry not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeEntryError(w, err)
+		return
+	}
+
+	entry, err := jc.journalService.PatchEntry(userID, entryID, r.Header.Get("Content-Type"), patch, expectedVersion)
+	if err != nil {
+		writeEntryError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", entryETag(entry))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -154,12 +189,32 @@ func (jc *JournalController) DeleteEntry(w http.ResponseWriter, r *http.Request)
 	// For MVP, use hardcoded user ID
 	userID := "user123"
 	
-	err := jc.journalService.DeleteEntry(userID, entryID)
+	expectedVersion, err := jc.expectedVersion(r, userID, entryID)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	err = jc.journalService.DeleteEntry(userID, entryID, expectedVersion)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Entry moved to trash",
+	})
+}
+
+// GET /trash
+func (jc *JournalController) GetTrash(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	entries, err := jc.journalService.GetTrash(userID)
 	if err != nil {
-		if err.Error() == "journal entry not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -167,6 +222,227 @@ func (jc *JournalController) DeleteEntry(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Entry deleted successfully",
+		"data":    entries,
 	})
-} 
\ No newline at end of file
+}
+
+// POST /trash/{id}/restore
+func (jc *JournalController) RestoreEntry(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	entry, err := jc.journalService.RestoreEntry(userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", entryETag(entry))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    entry,
+	})
+}
+
+// DELETE /trash/{id}
+func (jc *JournalController) PurgeEntry(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := jc.journalService.PurgeEntry(userID, mux.Vars(r)["id"]); err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Entry permanently deleted",
+	})
+}
+
+// GET /entries/{id}/revisions
+func (jc *JournalController) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	entryID := mux.Vars(r)["id"]
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	revisions, err := jc.journalService.GetRevisions(userID, entryID)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          true,
+		"data":             revisions,
+		"current_revision": len(revisions) + 1,
+	})
+}
+
+// GET /entries/{id}/revisions/{revision}
+func (jc *JournalController) GetRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	revision, err := jc.journalService.GetRevision(userID, vars["id"], vars["revision"])
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    revision,
+	})
+}
+
+// GET /entries/{id}/diff?from=1&to=current
+func (jc *JournalController) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" {
+		http.Error(w, "from revision is required", http.StatusBadRequest)
+		return
+	}
+	if to == "" {
+		to = "current"
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	diff, err := jc.journalService.DiffRevisions(userID, mux.Vars(r)["id"], from, to)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    diff,
+	})
+}
+
+// POST /entries/{id}/revisions/{revision}/restore
+func (jc *JournalController) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	expectedVersion, err := jc.expectedVersion(r, userID, vars["id"])
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	entry, err := jc.journalService.RestoreRevision(userID, vars["id"], vars["revision"], expectedVersion)
+	if err != nil {
+		writeEntryError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", entryETag(entry))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    entry,
+	})
+}
+
+func writeEntryError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "journal entry not found", err.Error() == "revision not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err.Error() == "journal entry version mismatch":
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case strings.HasPrefix(err.Error(), "unsupported patch content type"):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case strings.HasPrefix(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// expectedVersion turns an If-Match header into the entry version a write must find.
+// nil means the write is unconditional.
+func (jc *JournalController) expectedVersion(r *http.Request, userID, entryID string) (*int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	// With a single tag the version can be checked atomically by the write itself.
+	// A list needs the current version to know which of them to expect.
+	tags := strings.Split(header, ",")
+	if len(tags) == 1 {
+		if version, ok := parseEntryETag(tags[0], entryID); ok {
+			return &version, nil
+		}
+		return nil, fmt.Errorf("journal entry version mismatch")
+	}
+
+	entry, err := jc.journalService.GetEntryByID(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !etagListMatches(header, entryETag(entry), true) {
+		return nil, fmt.Errorf("journal entry version mismatch")
+	}
+	return &entry.Version, nil
+}
+
+func entryETag(entry *models.JournalEntry) string {
+	return fmt.Sprintf("\"%s-%d\"", entry.ID.Hex(), entry.Version)
+}
+
+// parseEntryETag reads the version out of a strong entry ETag.
+func parseEntryETag(tag, entryID string) (int64, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	id, version, found := strings.Cut(tag[1:len(tag)-1], "-")
+	if !found || id != entryID {
+		return 0, false
+	}
+	parsed, err := strconv.ParseInt(version, 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, false
+	}
+	return parsed, true
+}
+
+// etagListMatches checks an If-Match / If-None-Match header against an ETag. If-Match
+// uses strong comparison, so weak tags never match it.
+func etagListMatches(header, etag string, strong bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			if strong {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
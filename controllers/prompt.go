@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"soulprint-backend/models"
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type PromptController struct {
+	promptService   *services.PromptService
+	templateService *services.TemplateService
+}
+
+func NewPromptController(promptService *services.PromptService, templateService *services.TemplateService) *PromptController {
+	return &PromptController{
+		promptService:   promptService,
+		templateService: templateService,
+	}
+}
+
+// GET /prompts?category=gratitude
+func (pc *PromptController) GetPrompts(w http.ResponseWriter, r *http.Request) {
+	prompts, err := pc.promptService.GetPrompts(r.URL.Query().Get("category"))
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    prompts,
+	})
+}
+
+// GET /prompts/suggest?category=cbt&timezone=Europe/Berlin
+func (pc *PromptController) SuggestPrompt(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	loc := time.UTC
+	if tz := r.URL.Query().Get("timezone"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "invalid timezone", http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestion, err := pc.promptService.SuggestPrompt(userID, r.URL.Query().Get("category"), time.Now().In(loc))
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    suggestion,
+	})
+}
+
+// GET /templates
+func (pc *PromptController) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	templates, err := pc.templateService.GetTemplates(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    templates,
+	})
+}
+
+// GET /templates/{id}
+func (pc *PromptController) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	template, err := pc.templateService.GetTemplate(userID, mux.Vars(r)["id"])
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    template,
+	})
+}
+
+// POST /templates
+func (pc *PromptController) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	template, err := pc.templateService.CreateTemplate(userID, req)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    template,
+	})
+}
+
+// PUT /templates/{id}
+func (pc *PromptController) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	template, err := pc.templateService.UpdateTemplate(userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    template,
+	})
+}
+
+// DELETE /templates/{id}
+func (pc *PromptController) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := pc.templateService.DeleteTemplate(userID, mux.Vars(r)["id"]); err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Template deleted successfully",
+	})
+}
+
+func writePromptError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
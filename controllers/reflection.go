@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"soulprint-backend/models"
 	"soulprint-backend/services"
@@ -11,16 +12,18 @@ import (
 )
 
 type ReflectionController struct {
-	aiService *services.AIService
+	aiService    *services.AIService
+	batchService *services.ReflectionBatchService
 }
 
-func NewReflectionController(aiService *services.AIService) *ReflectionController {
+func NewReflectionController(aiService *services.AIService, batchService *services.ReflectionBatchService) *ReflectionController {
 	return &ReflectionController{
-		aiService: aiService,
+		aiService:    aiService,
+		batchService: batchService,
 	}
 }
 
-// POST /reflect
+// POST /reflect?force=true
 func (rc *ReflectionController) GenerateReflection(w http.ResponseWriter, r *http.Request) {
 	var req models.ReflectionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -34,15 +37,127 @@ func (rc *ReflectionController) GenerateReflection(w http.ResponseWriter, r *htt
 		return
 	}
 
+	if r.URL.Query().Get("force") == "true" {
+		req.Force = true
+	}
+
 	// For MVP, use hardcoded user ID
 	userID := "user123"
 	
 	reflection, err := rc.aiService.GenerateReflection(userID, req)
 	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid request") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A cached reflection was not created by this request.
+	status := http.StatusCreated
+	if reflection.Cached {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    reflection,
+	})
+}
+
+// POST /reflect/batch
+func (rc *ReflectionController) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchReflectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	batch, err := rc.batchService.CreateBatch(userID, req)
+	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/reflect/batch/"+batch.ID.Hex())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    batch,
+	})
+}
+
+// GET /reflect/batch/{id}
+func (rc *ReflectionController) GetBatch(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	batch, err := rc.batchService.GetBatch(userID, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case err.Error() == "batch not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    batch,
+	})
+}
+
+// POST /reflections/{id}/regenerate
+func (rc *ReflectionController) RegenerateReflection(w http.ResponseWriter, r *http.Request) {
+	var req models.RegenerateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	reflection, err := rc.aiService.RegenerateReflection(userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
+		switch {
+		case err.Error() == "reflection not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -51,6 +166,117 @@ func (rc *ReflectionController) GenerateReflection(w http.ResponseWriter, r *htt
 	})
 }
 
+// GET /reflections/{id}/versions
+func (rc *ReflectionController) GetReflectionVersions(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	versions, err := rc.aiService.GetReflectionVersions(userID, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case err.Error() == "reflection not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    versions,
+	})
+}
+
+// POST /reflections/{id}/feedback
+func (rc *ReflectionController) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	var req models.FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	feedback, err := rc.aiService.SubmitFeedback(userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		switch {
+		case err.Error() == "reflection not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    feedback,
+	})
+}
+
+// GET /reflections/{id}/feedback
+func (rc *ReflectionController) GetFeedback(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	feedback, err := rc.aiService.GetFeedback(userID, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case err.Error() == "feedback not found":
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    feedback,
+	})
+}
+
+// GET /admin/feedback?group_by=type,prompt_version,model
+func (rc *ReflectionController) GetFeedbackStats(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "type,prompt_version,model"
+	}
+	var fields []string
+	for _, field := range strings.Split(groupBy, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	stats, err := rc.aiService.GetFeedbackStats(fields)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    stats,
+	})
+}
+
 // GET /insights
 func (rc *ReflectionController) GetInsights(w http.ResponseWriter, r *http.Request) {
 	// For MVP, use hardcoded user ID
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"soulprint-backend/models"
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ReminderController struct {
+	reminderService *services.ReminderService
+}
+
+func NewReminderController(reminderService *services.ReminderService) *ReminderController {
+	return &ReminderController{
+		reminderService: reminderService,
+	}
+}
+
+// POST /reminders
+func (rc *ReminderController) CreateReminder(w http.ResponseWriter, r *http.Request) {
+	var req models.ReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	reminder, err := rc.reminderService.CreateReminder(userID, req)
+	if err != nil {
+		writeReminderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    reminder,
+	})
+}
+
+// GET /reminders
+func (rc *ReminderController) GetReminders(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	reminders, err := rc.reminderService.GetReminders(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    reminders,
+	})
+}
+
+// PUT /reminders/{id}
+func (rc *ReminderController) UpdateReminder(w http.ResponseWriter, r *http.Request) {
+	var req models.ReminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	reminder, err := rc.reminderService.UpdateReminder(userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		writeReminderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    reminder,
+	})
+}
+
+// DELETE /reminders/{id}
+func (rc *ReminderController) DeleteReminder(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := rc.reminderService.DeleteReminder(userID, mux.Vars(r)["id"]); err != nil {
+		writeReminderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Reminder deleted successfully",
+	})
+}
+
+// POST /reminders/{id}/test
+func (rc *ReminderController) TestReminder(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := rc.reminderService.SendTestReminder(userID, mux.Vars(r)["id"]); err != nil {
+		if err.Error() == "reminder not found" || strings.HasPrefix(err.Error(), "invalid") {
+			writeReminderError(w, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Test reminder sent",
+	})
+}
+
+func writeReminderError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "reminder not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ReportController struct {
+	reportService *services.ReportService
+}
+
+func NewReportController(reportService *services.ReportService) *ReportController {
+	return &ReportController{
+		reportService: reportService,
+	}
+}
+
+// GET /reports/year/{year}?format=json|markdown|html&refresh=true
+func (rc *ReportController) GetYearReview(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	refresh := r.URL.Query().Get("refresh") == "true"
+	review, err := rc.reportService.GetYearReview(userID, year, refresh)
+	if err != nil {
+		if writeAIError(w, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "no journal entries"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "markdown", "md":
+		body, err := services.RenderYearReviewMarkdown(review)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write(body)
+	case "html":
+		body, err := services.RenderYearReviewHTML(review)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    review,
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"soulprint-backend/models"
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type SettingsController struct {
+	settingsService *services.SettingsService
+}
+
+func NewSettingsController(settingsService *services.SettingsService) *SettingsController {
+	return &SettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// GET /settings
+func (sc *SettingsController) GetSettings(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	settings, err := sc.settingsService.GetSettings(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    settings,
+	})
+}
+
+// PUT /settings
+func (sc *SettingsController) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.SettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	settings, err := sc.settingsService.UpdateSettings(userID, req)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    settings,
+	})
+}
+
+// GET /keys/backups
+func (sc *SettingsController) GetKeyBackups(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	backups, err := sc.settingsService.GetKeyBackups(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    backups,
+	})
+}
+
+// GET /keys/backups/{keyId}
+func (sc *SettingsController) GetKeyBackup(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	backup, err := sc.settingsService.GetKeyBackup(userID, mux.Vars(r)["keyId"])
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    backup,
+	})
+}
+
+// PUT /keys/backups/{keyId}
+func (sc *SettingsController) PutKeyBackup(w http.ResponseWriter, r *http.Request) {
+	var req models.KeyBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	backup, err := sc.settingsService.PutKeyBackup(userID, mux.Vars(r)["keyId"], req)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    backup,
+	})
+}
+
+// DELETE /keys/backups/{keyId}
+func (sc *SettingsController) DeleteKeyBackup(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := sc.settingsService.DeleteKeyBackup(userID, mux.Vars(r)["keyId"]); err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Key backup deleted successfully",
+	})
+}
+
+func writeSettingsError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "key backup not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TopicController struct {
+	topicService *services.TopicService
+}
+
+func NewTopicController(topicService *services.TopicService) *TopicController {
+	return &TopicController{
+		topicService: topicService,
+	}
+}
+
+// GET /topics?include_retired=true
+func (tc *TopicController) GetTopics(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	topics, err := tc.topicService.GetTopics(userID, r.URL.Query().Get("include_retired") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    topics,
+	})
+}
+
+// POST /topics/refresh
+func (tc *TopicController) RefreshTopics(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	topics, err := tc.topicService.RefreshTopics(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    topics,
+	})
+}
+
+// GET /topics/{id}/entries
+func (tc *TopicController) GetTopicEntries(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	entries, err := tc.topicService.GetTopicEntries(userID, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    entries,
+	})
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"soulprint-backend/services"
+	"soulprint-backend/utils"
+)
+
+type UsageController struct {
+	usageService *services.UsageService
+}
+
+func NewUsageController(usageService *services.UsageService) *UsageController {
+	return &UsageController{
+		usageService: usageService,
+	}
+}
+
+// GET /usage
+func (uc *UsageController) GetUsage(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	usage, err := uc.usageService.GetUserUsage(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    usage,
+	})
+}
+
+// GET /admin/usage?from=2024-01-01&to=2024-02-01 (defaults to the last 30 days)
+func (uc *UsageController) GetGlobalUsage(w http.ResponseWriter, r *http.Request) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	usage, err := uc.usageService.GetGlobalUsage(from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    usage,
+	})
+}
+
+// writeAIError handles the errors callers of the AI provider share: an exceeded token
+// budget gets 429 with a Retry-After header, and a provider without credentials gets
+// 503. It reports whether it responded.
+func writeAIError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, utils.ErrAINotConfigured) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return true
+	}
+	var budgetErr *services.BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		return false
+	}
+	retryAfter := int(time.Until(budgetErr.ResetsAt).Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, budgetErr.Error(), http.StatusTooManyRequests)
+	return true
+}
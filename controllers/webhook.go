@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"soulprint-backend/models"
+	"soulprint-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// POST /webhooks
+func (wc *WebhookController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	subscription, err := wc.webhookService.CreateSubscription(userID, req)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    subscription,
+		"message": "Store the secret now, it will not be shown again",
+	})
+}
+
+// GET /webhooks
+func (wc *WebhookController) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	subscriptions, err := wc.webhookService.GetSubscriptions(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    subscriptions,
+	})
+}
+
+// PUT /webhooks/{id}
+func (wc *WebhookController) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	subscription, err := wc.webhookService.UpdateSubscription(userID, mux.Vars(r)["id"], req)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    subscription,
+	})
+}
+
+// DELETE /webhooks/{id}
+func (wc *WebhookController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	if err := wc.webhookService.DeleteSubscription(userID, mux.Vars(r)["id"]); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GET /webhooks/{id}/deliveries?limit=50
+func (wc *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := int64(50)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	deliveries, err := wc.webhookService.GetDeliveries(userID, mux.Vars(r)["id"], limit)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    deliveries,
+	})
+}
+
+// POST /webhooks/{id}/ping
+func (wc *WebhookController) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	// For MVP, use hardcoded user ID
+	userID := "user123"
+
+	delivery, err := wc.webhookService.Ping(userID, mux.Vars(r)["id"])
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": delivery.Status == "succeeded",
+		"data":    delivery,
+	})
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "webhook not found":
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
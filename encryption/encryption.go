@@ -0,0 +1,337 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Encrypted fields are stored as "enc:v1:<data key id>:<base64 nonce+ciphertext>".
+const prefix = "enc:v1:"
+
+// Other replicas (or cmd/rotatekeys) may rotate a user's key; the active key is
+// looked up again after this long.
+const activeKeyTTL = time.Minute
+
+type activeKey struct {
+	id      primitive.ObjectID
+	expires time.Time
+}
+
+// Encryptor encrypts individual document fields with AES-256-GCM using per-user data
+// keys. Data keys live in the data_keys collection, wrapped by the master key. A nil
+// *Encryptor leaves values untouched, so encryption can be switched off by not
+// configuring a master key.
+type Encryptor struct {
+	keys     *mongo.Collection
+	master   cipher.AEAD
+	masterID string
+
+	mu     sync.Mutex
+	aeads  map[primitive.ObjectID]cipher.AEAD
+	active map[string]activeKey
+}
+
+func New(client *mongo.Client, masterKey []byte) (*Encryptor, error) {
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	return &Encryptor{
+		keys:     client.Database(config.AppConfig.MongoDatabase).Collection("data_keys"),
+		master:   master,
+		masterID: MasterKeyID(masterKey),
+		aeads:    make(map[primitive.ObjectID]cipher.AEAD),
+		active:   make(map[string]activeKey),
+	}, nil
+}
+
+// LoadMasterKey reads the master key from ENCRYPTION_MASTER_KEY or, failing that, the
+// file named by ENCRYPTION_KEY_FILE. It returns nil when neither is configured.
+func LoadMasterKey() ([]byte, error) {
+	if config.AppConfig.EncryptionMasterKey != "" {
+		return ParseMasterKey(config.AppConfig.EncryptionMasterKey)
+	}
+	if config.AppConfig.EncryptionKeyFile != "" {
+		return ReadMasterKeyFile(config.AppConfig.EncryptionKeyFile)
+	}
+	return nil, nil
+}
+
+// ParseMasterKey decodes a base64 encoded 32 byte master key.
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid master key: want 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// ReadMasterKeyFile reads a master key file holding the base64 encoded key.
+func ReadMasterKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	return ParseMasterKey(string(data))
+}
+
+// GenerateMasterKey returns a new random master key, base64 encoded.
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// MasterKeyID identifies a master key without revealing it, so data keys record
+// which master key wrapped them.
+func MasterKeyID(masterKey []byte) string {
+	sum := sha256.Sum256(masterKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// IsEncrypted reports whether a stored value is ciphertext produced by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts plaintext with the user's active data key, creating the key on
+// first use. The user ID is bound to the ciphertext, so values cannot be moved
+// between users.
+func (e *Encryptor) Encrypt(userID, plaintext string) (string, error) {
+	if e == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	keyID, aead, err := e.activeKey(userID)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(userID))
+	return prefix + keyID.Hex() + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt. Values that are not encrypted (written before encryption
+// was enabled) are returned unchanged.
+func (e *Encryptor) Decrypt(userID, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if e == nil {
+		return "", fmt.Errorf("value is encrypted but no master key is configured")
+	}
+
+	keyID, payload, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	aead, err := e.dataKey(userID, keyID)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(userID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// EnsureIndexes creates the index used to find a user's active data key.
+func (e *Encryptor) EnsureIndexes(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	_, err := e.keys.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	return err
+}
+
+func (e *Encryptor) activeKey(userID string) (primitive.ObjectID, cipher.AEAD, error) {
+	e.mu.Lock()
+	cached, ok := e.active[userID]
+	e.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		aead, err := e.dataKey(userID, cached.id)
+		return cached.id, aead, err
+	}
+
+	var key models.DataKey
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := e.keys.FindOne(context.Background(), bson.M{"user_id": userID, "retired_at": nil}, opts).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		id, err := e.createKey(userID)
+		if err != nil {
+			return primitive.NilObjectID, nil, err
+		}
+		aead, err := e.dataKey(userID, id)
+		return id, aead, err
+	}
+	if err != nil {
+		return primitive.NilObjectID, nil, fmt.Errorf("failed to find data key: %w", err)
+	}
+
+	aead, err := e.unwrap(&key)
+	if err != nil {
+		return primitive.NilObjectID, nil, err
+	}
+	e.remember(userID, key.ID, aead)
+	return key.ID, aead, nil
+}
+
+func (e *Encryptor) dataKey(userID string, id primitive.ObjectID) (cipher.AEAD, error) {
+	e.mu.Lock()
+	aead, ok := e.aeads[id]
+	e.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	var key models.DataKey
+	err := e.keys.FindOne(context.Background(), bson.M{"_id": id, "user_id": userID}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("data key %s not found", id.Hex())
+		}
+		return nil, fmt.Errorf("failed to find data key: %w", err)
+	}
+
+	aead, err = e.unwrap(&key)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.aeads[id] = aead
+	e.mu.Unlock()
+	return aead, nil
+}
+
+func (e *Encryptor) createKey(userID string) (primitive.ObjectID, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	key := models.DataKey{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		MasterKeyID: e.masterID,
+		CreatedAt:   time.Now(),
+	}
+	wrapped, err := wrap(e.master, raw, &key)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	key.WrappedKey = wrapped
+
+	if _, err := e.keys.InsertOne(context.Background(), key); err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to store data key: %w", err)
+	}
+
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	e.remember(userID, key.ID, aead)
+	return key.ID, nil
+}
+
+func (e *Encryptor) unwrap(key *models.DataKey) (cipher.AEAD, error) {
+	if key.MasterKeyID != e.masterID {
+		return nil, fmt.Errorf("data key %s is wrapped by master key %s, not the configured one", key.ID.Hex(), key.MasterKeyID)
+	}
+	raw, err := unwrap(e.master, key)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(raw)
+}
+
+func (e *Encryptor) remember(userID string, id primitive.ObjectID, aead cipher.AEAD) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.aeads[id] = aead
+	e.active[userID] = activeKey{id: id, expires: time.Now().Add(activeKeyTTL)}
+}
+
+// wrap encrypts a raw data key with the master key. The key's ID and owner are bound
+// as additional data.
+func wrap(master cipher.AEAD, raw []byte, key *models.DataKey) ([]byte, error) {
+	nonce := make([]byte, master.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return master.Seal(nonce, nonce, raw, keyAAD(key)), nil
+}
+
+func unwrap(master cipher.AEAD, key *models.DataKey) ([]byte, error) {
+	if len(key.WrappedKey) < master.NonceSize() {
+		return nil, fmt.Errorf("data key %s is malformed", key.ID.Hex())
+	}
+	nonce, sealed := key.WrappedKey[:master.NonceSize()], key.WrappedKey[master.NonceSize():]
+	raw, err := master.Open(nil, nonce, sealed, keyAAD(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", key.ID.Hex(), err)
+	}
+	return raw, nil
+}
+
+func keyAAD(key *models.DataKey) []byte {
+	return []byte(key.UserID + ":" + key.ID.Hex())
+}
+
+func parse(value string) (primitive.ObjectID, []byte, error) {
+	keyHex, encoded, found := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !found {
+		return primitive.NilObjectID, nil, fmt.Errorf("malformed encrypted value")
+	}
+	keyID, err := primitive.ObjectIDFromHex(keyHex)
+	if err != nil {
+		return primitive.NilObjectID, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return primitive.NilObjectID, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	return keyID, payload, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
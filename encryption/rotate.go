@@ -0,0 +1,243 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RotateUserKey creates a new data key for the user. It becomes the active key right
+// away in this process and within activeKeyTTL everywhere else.
+func (e *Encryptor) RotateUserKey(userID string) (primitive.ObjectID, error) {
+	return e.createKey(userID)
+}
+
+// RetireKeys marks every key of the user except keep as retired. Retired keys are no
+// longer used for encryption but are kept so that any value still sealed with them
+// remains readable.
+func (e *Encryptor) RetireKeys(ctx context.Context, userID string, keep primitive.ObjectID) (int64, error) {
+	result, err := e.keys.UpdateMany(ctx,
+		bson.M{"user_id": userID, "_id": bson.M{"$ne": keep}, "retired_at": nil},
+		bson.M{"$set": bson.M{"retired_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retire data keys: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// Rewrap re-encrypts every data key wrapped by oldMaster with the configured master
+// key. The data keys themselves, and so all encrypted fields, are unchanged.
+func (e *Encryptor) Rewrap(ctx context.Context, oldMaster []byte) (int, error) {
+	old, err := newAEAD(oldMaster)
+	if err != nil {
+		return 0, fmt.Errorf("invalid old master key: %w", err)
+	}
+	oldID := MasterKeyID(oldMaster)
+	if oldID == e.masterID {
+		return 0, fmt.Errorf("old and new master keys are the same")
+	}
+
+	cursor, err := e.keys.Find(ctx, bson.M{"master_key_id": oldID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find data keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rewrapped := 0
+	for cursor.Next(ctx) {
+		var key models.DataKey
+		if err := cursor.Decode(&key); err != nil {
+			return rewrapped, fmt.Errorf("failed to decode data key: %w", err)
+		}
+		raw, err := unwrap(old, &key)
+		if err != nil {
+			return rewrapped, err
+		}
+		wrapped, err := wrap(e.master, raw, &key)
+		if err != nil {
+			return rewrapped, err
+		}
+		_, err = e.keys.UpdateOne(ctx,
+			bson.M{"_id": key.ID, "master_key_id": oldID},
+			bson.M{"$set": bson.M{"wrapped_key": wrapped, "master_key_id": e.masterID}},
+		)
+		if err != nil {
+			return rewrapped, fmt.Errorf("failed to update data key: %w", err)
+		}
+		rewrapped++
+	}
+	return rewrapped, cursor.Err()
+}
+
+// Reencrypt rewrites the given fields of a user's documents with the user's active
+// data key. Fields are dotted paths; a path through an array applies to each element
+// ("highlights.title"). Plaintext values are encrypted too, which migrates data
+// written before encryption was enabled. A document that changes while it is being
+// rewritten is left alone and counted as skipped; running again picks it up.
+func (e *Encryptor) Reencrypt(ctx context.Context, coll *mongo.Collection, userID string, fields []string) (updated, skipped int, err error) {
+	keyID, _, err := e.activeKey(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find documents in %s: %w", coll.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, skipped, fmt.Errorf("failed to decode document: %w", err)
+		}
+
+		filter := bson.D{{Key: "_id", Value: lookup(doc, "_id")}}
+		set := bson.D{}
+		for _, top := range topLevel(fields) {
+			original := lookup(doc, top)
+			if original == nil {
+				continue
+			}
+			rewritten, changed, err := e.rewrite(userID, keyID, original, subPaths(fields, top))
+			if err != nil {
+				return updated, skipped, fmt.Errorf("%s %v: %w", coll.Name(), filter[0].Value, err)
+			}
+			if changed {
+				// Matching on the old value guards against overwriting a concurrent edit.
+				filter = append(filter, bson.E{Key: top, Value: original})
+				set = append(set, bson.E{Key: top, Value: rewritten})
+			}
+		}
+		if len(set) == 0 {
+			continue
+		}
+
+		result, err := coll.UpdateOne(ctx, filter, bson.M{"$set": set})
+		if err != nil {
+			return updated, skipped, fmt.Errorf("failed to update document: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			skipped++
+		} else {
+			updated++
+		}
+	}
+	return updated, skipped, cursor.Err()
+}
+
+// rewrite re-encrypts the strings found at paths (relative to value). An empty path
+// means value itself.
+func (e *Encryptor) rewrite(userID string, keyID primitive.ObjectID, value interface{}, paths [][]string) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case string:
+		if !hasEmptyPath(paths) || v == "" {
+			return v, false, nil
+		}
+		if id, _, err := parse(v); IsEncrypted(v) && err == nil && id == keyID {
+			return v, false, nil
+		}
+		plaintext, err := e.Decrypt(userID, v)
+		if err != nil {
+			return nil, false, err
+		}
+		sealed, err := e.Encrypt(userID, plaintext)
+		return sealed, err == nil, err
+	case primitive.A:
+		out := make(primitive.A, len(v))
+		changed := false
+		for i, item := range v {
+			rewritten, itemChanged, err := e.rewrite(userID, keyID, item, paths)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = rewritten
+			changed = changed || itemChanged
+		}
+		return out, changed, nil
+	case primitive.D:
+		out := make(primitive.D, len(v))
+		copy(out, v)
+		changed := false
+		for _, top := range topLevelPaths(paths) {
+			for i := range out {
+				if out[i].Key != top {
+					continue
+				}
+				rewritten, fieldChanged, err := e.rewrite(userID, keyID, out[i].Value, subPathsOf(paths, top))
+				if err != nil {
+					return nil, false, err
+				}
+				out[i].Value = rewritten
+				changed = changed || fieldChanged
+			}
+		}
+		return out, changed, nil
+	default:
+		return value, false, nil
+	}
+}
+
+func lookup(doc bson.D, key string) interface{} {
+	for _, elem := range doc {
+		if elem.Key == key {
+			return elem.Value
+		}
+	}
+	return nil
+}
+
+func topLevel(fields []string) []string {
+	return topLevelPaths(splitPaths(fields))
+}
+
+func subPaths(fields []string, top string) [][]string {
+	return subPathsOf(splitPaths(fields), top)
+}
+
+func splitPaths(fields []string) [][]string {
+	paths := make([][]string, len(fields))
+	for i, field := range fields {
+		paths[i] = strings.Split(field, ".")
+	}
+	return paths
+}
+
+func topLevelPaths(paths [][]string) []string {
+	var tops []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if len(path) > 0 && !seen[path[0]] {
+			seen[path[0]] = true
+			tops = append(tops, path[0])
+		}
+	}
+	return tops
+}
+
+func subPathsOf(paths [][]string, top string) [][]string {
+	var rest [][]string
+	for _, path := range paths {
+		if len(path) > 0 && path[0] == top {
+			rest = append(rest, path[1:])
+		}
+	}
+	return rest
+}
+
+func hasEmptyPath(paths [][]string) bool {
+	for _, path := range paths {
+		if len(path) == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package fakellm
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embed returns a deterministic unit vector for text. Each word is hashed into a
+// few dimensions, so texts that share words point in similar directions, which is
+// enough for tests of similarity search and clustering.
+func Embed(text string, dimensions int) []float64 {
+	vector := make([]float64, dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for i := 0; i < 3; i++ {
+			index := int(sum % uint64(dimensions))
+			sign := 1.0
+			if sum&(1<<32) != 0 {
+				sign = -1.0
+			}
+			vector[index] += sign
+			sum = sum*6364136223846793005 + 1442695040888963407
+		}
+	}
+
+	norm := 0.0
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		vector[0] = 1
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
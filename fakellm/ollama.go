@@ -0,0 +1,133 @@
+package fakellm
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// POST /api/generate
+func (s *Server) ollamaGenerate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+		System string `json:"system"`
+		Stream *bool  `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	s.ollamaRespond(w, "generate", s.modelName(req.Model), req.System, req.Prompt, req.Stream, func(base map[string]interface{}, content string) {
+		base["response"] = content
+	})
+}
+
+// POST /api/chat
+func (s *Server) ollamaChat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string          `json:"model"`
+		Messages []ollamaMessage `json:"messages"`
+		Stream   *bool           `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	messages := make([]openAIMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		messages[i] = openAIMessage(message)
+	}
+	system, prompt := splitMessages(messages)
+	s.ollamaRespond(w, "chat", s.modelName(req.Model), system, prompt, req.Stream, func(base map[string]interface{}, content string) {
+		base["message"] = ollamaMessage{Role: "assistant", Content: content}
+	})
+}
+
+// ollamaRespond answers a generate or chat request. Ollama streams unless the
+// request sets "stream": false; set adds the endpoint's content field to a message.
+func (s *Server) ollamaRespond(w http.ResponseWriter, endpoint, model, system, prompt string, streamFlag *bool, set func(base map[string]interface{}, content string)) {
+	stream := streamFlag == nil || *streamFlag
+	rule := s.record(Request{Endpoint: endpoint, Provider: "ollama", Model: model, System: system, Prompt: prompt, Stream: stream})
+	if !stream {
+		s.pause(rule)
+	} else if rule != nil {
+		time.Sleep(rule.Delay())
+	}
+	if fault(rule, func(status int, message string) { writeOllamaError(w, status, message) }) {
+		return
+	}
+	text, err := s.reply(rule, system, prompt)
+	if err != nil {
+		writeOllamaError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message := func(content string, done bool) map[string]interface{} {
+		base := map[string]interface{}{
+			"model":      model,
+			"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"done":       done,
+		}
+		set(base, content)
+		if done {
+			base["done_reason"] = "stop"
+			base["prompt_eval_count"] = countWords(system, prompt)
+			base["eval_count"] = countWords(text)
+		}
+		return base
+	}
+
+	if !stream {
+		writeJSON(w, rule, message(text, true))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	parts := tokens(text)
+	events := make([]interface{}, 0, len(parts)+1)
+	for _, part := range parts {
+		events = append(events, message(part, false))
+	}
+	events = append(events, message("", true))
+	s.stream(w, rule, events, func(event interface{}) []byte {
+		data, _ := json.Marshal(event)
+		return append(data, '\n')
+	})
+}
+
+// POST /api/embeddings
+func (s *Server) ollamaEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	rule := s.record(Request{Endpoint: "embeddings", Provider: "ollama", Model: s.modelName(req.Model), Prompt: req.Prompt})
+	s.pause(rule)
+	if fault(rule, func(status int, message string) { writeOllamaError(w, status, message) }) {
+		return
+	}
+	writeJSON(w, rule, map[string]interface{}{"embedding": Embed(req.Prompt, s.opts.Dimensions)})
+}
+
+// GET /api/tags lists the one model the server pretends to have.
+func (s *Server) ollamaTags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, nil, map[string]interface{}{
+		"models": []map[string]interface{}{{"name": s.opts.Model, "model": s.opts.Model}},
+	})
+}
+
+func writeOllamaError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
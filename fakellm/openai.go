@@ -0,0 +1,173 @@
+package fakellm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var completionIDs atomic.Int64
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// POST /v1/chat/completions
+func (s *Server) openAIChat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string          `json:"model"`
+		Messages []openAIMessage `json:"messages"`
+		Stream   bool            `json:"stream"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	system, prompt := splitMessages(req.Messages)
+	model := s.modelName(req.Model)
+
+	rule := s.record(Request{Endpoint: "chat", Provider: "openai", Model: model, System: system, Prompt: prompt, Stream: req.Stream})
+	if !req.Stream {
+		s.pause(rule)
+	} else if rule != nil {
+		time.Sleep(rule.Delay())
+	}
+	if fault(rule, func(status int, message string) { writeOpenAIError(w, status, message) }) {
+		return
+	}
+	text, err := s.reply(rule, system, prompt)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	id := fmt.Sprintf("chatcmpl-fake-%d", completionIDs.Add(1))
+	created := time.Now().Unix()
+	if !req.Stream {
+		writeJSON(w, rule, map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       openAIMessage{Role: "assistant", Content: text},
+				"finish_reason": "stop",
+			}},
+			"usage": openAIUsage{
+				PromptTokens:     countWords(system, prompt),
+				CompletionTokens: countWords(text),
+				TotalTokens:      countWords(system, prompt, text),
+			},
+		})
+		return
+	}
+
+	chunk := func(delta map[string]string, finishReason interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	parts := tokens(text)
+	events := make([]interface{}, 0, len(parts)+2)
+	events = append(events, chunk(map[string]string{"role": "assistant", "content": ""}, nil))
+	for _, part := range parts {
+		events = append(events, chunk(map[string]string{"content": part}, nil))
+	}
+	events = append(events, chunk(map[string]string{}, "stop"))
+	s.stream(w, rule, events, func(event interface{}) []byte {
+		data, _ := json.Marshal(event)
+		return []byte("data: " + string(data) + "\n\n")
+	})
+	if rule == nil || !rule.Truncate {
+		w.Write([]byte("data: [DONE]\n\n"))
+	}
+}
+
+// POST /v1/embeddings
+func (s *Server) openAIEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	// The input is a string or a list of strings.
+	var inputs []string
+	if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(req.Input, &input); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "input must be a string or a list of strings")
+			return
+		}
+		inputs = []string{input}
+	}
+	model := s.modelName(req.Model)
+
+	joined := ""
+	for _, input := range inputs {
+		joined += input + "\n"
+	}
+	rule := s.record(Request{Endpoint: "embeddings", Provider: "openai", Model: model, Prompt: joined})
+	s.pause(rule)
+	if fault(rule, func(status int, message string) { writeOpenAIError(w, status, message) }) {
+		return
+	}
+
+	data := make([]map[string]interface{}, len(inputs))
+	for i, input := range inputs {
+		data[i] = map[string]interface{}{"object": "embedding", "index": i, "embedding": Embed(input, s.opts.Dimensions)}
+	}
+	tokens := countWords(inputs...)
+	writeJSON(w, rule, map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  model,
+		"usage":  map[string]int{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+}
+
+// splitMessages returns the system messages and the last user message.
+func splitMessages(messages []openAIMessage) (system, prompt string) {
+	for _, message := range messages {
+		switch message.Role {
+		case "system":
+			if system != "" {
+				system += "\n"
+			}
+			system += message.Content
+		case "user":
+			prompt = message.Content
+		}
+	}
+	return system, prompt
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "fake_error",
+			"code":    nil,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package fakellm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Rule scripts the answer to matching requests. Empty match fields match anything.
+type Rule struct {
+	Name string `json:"name"`
+	// Endpoint is "chat", "generate" or "embeddings"; Provider is "openai" or "ollama".
+	Endpoint string `json:"endpoint,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	// Match is a regular expression tried against the system prompt and the prompt.
+	Match string `json:"match,omitempty"`
+	// Times limits how often the rule applies; 0 means always.
+	Times int `json:"times,omitempty"`
+
+	// Response replaces the generated text.
+	Response string `json:"response,omitempty"`
+
+	// Faults.
+	Status     int    `json:"status,omitempty"`     // respond with this HTTP error status
+	Error      string `json:"error,omitempty"`      // error message for Status, defaults to the status text
+	DelayMS    int    `json:"delay_ms,omitempty"`   // wait before answering
+	Malformed  bool   `json:"malformed,omitempty"`  // send a body that is not valid JSON
+	Truncate   bool   `json:"truncate,omitempty"`   // stop a stream halfway, or cut a body short
+	Disconnect bool   `json:"disconnect,omitempty"` // close the connection without answering
+}
+
+func (r Rule) Delay() time.Duration {
+	return time.Duration(r.DelayMS) * time.Millisecond
+}
+
+// LoadRules reads a JSON file holding {"rules": [...]}.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	for _, r := range file.Rules {
+		if _, err := regexp.Compile(r.Match); err != nil {
+			return nil, fmt.Errorf("rule %q: invalid match: %w", r.Name, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+type rule struct {
+	Rule
+	match *regexp.Regexp
+	used  int
+}
+
+// newRule compiles a rule. An invalid pattern matches nothing; LoadRules reports it.
+func newRule(r Rule) *rule {
+	compiled := &rule{Rule: r}
+	if r.Match != "" {
+		compiled.match, _ = regexp.Compile(r.Match)
+		if compiled.match == nil {
+			compiled.match = regexp.MustCompile(`$^`)
+		}
+	}
+	return compiled
+}
+
+func (r *rule) matches(req Request) bool {
+	if r.Times > 0 && r.used >= r.Times {
+		return false
+	}
+	if r.Endpoint != "" && r.Endpoint != req.Endpoint {
+		return false
+	}
+	if r.Provider != "" && r.Provider != req.Provider {
+		return false
+	}
+	return r.match == nil || r.match.MatchString(req.System) || r.match.MatchString(req.Prompt)
+}
+
+// fault writes the rule's error status or drops the connection, and reports whether
+// it did. Malformed and truncated bodies are left to the endpoint, which knows the
+// body's shape.
+func fault(rule *Rule, writeError func(status int, message string)) bool {
+	if rule == nil {
+		return false
+	}
+	if rule.Disconnect {
+		// The server closes the connection without writing a response.
+		panic(http.ErrAbortHandler)
+	}
+	if rule.Status != 0 {
+		message := rule.Error
+		if message == "" {
+			message = http.StatusText(rule.Status)
+		}
+		writeError(rule.Status, message)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+// Package fakellm is an HTTP stand-in for OpenAI and Ollama. It serves the OpenAI
+// chat completions and embeddings endpoints and Ollama's /api/generate, /api/chat and
+// /api/embeddings, streaming included. Responses come from utils.FakeModel unless a
+// scripted rule matches, and rules can also inject faults: error statuses, delays,
+// malformed bodies, truncated streams and dropped connections.
+//
+// In tests, wrap it with httptest:
+//
+//	srv := httptest.NewServer(fakellm.New(fakellm.Options{}))
+//	defer srv.Close()
+//	client, _ := utils.NewClient(utils.Provider{Name: "openai", URL: srv.URL + "/v1"})
+//
+// cmd/fakellm runs it as a standalone server.
+package fakellm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"soulprint-backend/utils"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Model is reported in responses when the request names none.
+	Model string
+	// Latency delays every response. Streams spread it over the tokens.
+	Latency time.Duration
+	// ErrorRate is the fraction of generated responses that fail with 500. See
+	// utils.FakeOptions for how failing requests are chosen.
+	ErrorRate float64
+	// Dimensions is the length of embedding vectors, 64 by default.
+	Dimensions int
+	Rules      []Rule
+}
+
+// Request is a request the server received, as recorded for assertions.
+type Request struct {
+	Endpoint string // "chat", "generate", "embeddings" (OpenAI or Ollama)
+	Provider string // "openai" or "ollama"
+	Model    string
+	System   string
+	Prompt   string // the last user message, the generate prompt or the embedding input
+	Stream   bool
+	Rule     string // name of the rule that answered, if any
+	Time     time.Time
+}
+
+type Server struct {
+	opts  Options
+	model *utils.FakeModel
+	mux   *http.ServeMux
+
+	mu       sync.Mutex
+	rules    []*rule
+	requests []Request
+}
+
+func New(opts Options) *Server {
+	if opts.Model == "" {
+		opts.Model = "fake"
+	}
+	if opts.Dimensions <= 0 {
+		opts.Dimensions = 64
+	}
+	s := &Server{
+		opts:  opts,
+		model: utils.NewFakeModel(opts.Model, utils.FakeOptions{ErrorRate: opts.ErrorRate}),
+		mux:   http.NewServeMux(),
+	}
+	for _, r := range opts.Rules {
+		s.AddRule(r)
+	}
+
+	s.mux.HandleFunc("/v1/chat/completions", s.openAIChat)
+	s.mux.HandleFunc("/v1/embeddings", s.openAIEmbeddings)
+	s.mux.HandleFunc("/api/generate", s.ollamaGenerate)
+	s.mux.HandleFunc("/api/chat", s.ollamaChat)
+	s.mux.HandleFunc("/api/embeddings", s.ollamaEmbeddings)
+	s.mux.HandleFunc("/api/tags", s.ollamaTags)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.URL.Path != "/api/tags" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddRule appends a scripted rule. Rules are tried in the order they were added.
+func (s *Server) AddRule(r Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, newRule(r))
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the recorded requests and the rules' use counts.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	for _, r := range s.rules {
+		r.used = 0
+	}
+}
+
+// record logs a request and returns the first rule that matches it, if any.
+func (s *Server) record(req Request) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched *Rule
+	for _, r := range s.rules {
+		if r.matches(req) {
+			r.used++
+			matched = &r.Rule
+			req.Rule = r.Name
+			break
+		}
+	}
+	req.Time = time.Now()
+	s.requests = append(s.requests, req)
+	return matched
+}
+
+// reply produces the text for a completion request: the rule's response if it has
+// one, otherwise the fake model's.
+func (s *Server) reply(rule *Rule, system, prompt string) (string, error) {
+	if rule != nil && rule.Response != "" {
+		return rule.Response, nil
+	}
+	return s.model.Complete(operation(system, prompt), system, prompt)
+}
+
+// operation guesses which of the client's operations a prompt belongs to, so the
+// fake model picks a fitting template.
+func operation(system, prompt string) string {
+	text := system + "\n" + prompt
+	switch {
+	case strings.Contains(text, "screen journal entries"):
+		return "safety"
+	case strings.Contains(text, "grade reflections"):
+		return "judge"
+	case strings.Contains(text, "key themes or keywords"):
+		return "keywords"
+	case strings.Contains(text, "Summarize the following journal entries"):
+		return "summary"
+	case strings.Contains(text, "across many journal entries"):
+		return "digest"
+	}
+	return "reflection"
+}
+
+// pause waits out the configured latency plus the rule's delay before a response
+// that is not streamed.
+func (s *Server) pause(rule *Rule) {
+	delay := s.opts.Latency
+	if rule != nil {
+		delay += rule.Delay()
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// tokenDelay is the pause between streamed tokens.
+func (s *Server) tokenDelay(tokens int) time.Duration {
+	if tokens == 0 {
+		return 0
+	}
+	return s.opts.Latency / time.Duration(tokens)
+}
+
+// tokens splits a response into streamed chunks: words with their trailing space.
+func tokens(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.SplitAfter(text, " ")
+}
+
+func countWords(texts ...string) int {
+	n := 0
+	for _, text := range texts {
+		n += len(strings.Fields(text))
+	}
+	return n
+}
+
+// modelName echoes the requested model, as the real APIs do.
+func (s *Server) modelName(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return s.opts.Model
+}
+
+// writeJSON writes a response body, cut short or garbled if the rule says so.
+func writeJSON(w http.ResponseWriter, rule *Rule, body interface{}) {
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case rule != nil && rule.Malformed:
+		data = []byte("{\"this is\": not json")
+	case rule != nil && rule.Truncate:
+		data = data[:len(data)/2]
+	}
+	w.Write(data)
+}
+
+// stream writes events one by one, flushing after each and pausing between them.
+// A truncating rule stops it halfway; a malformed one garbles the second event.
+func (s *Server) stream(w http.ResponseWriter, rule *Rule, events []interface{}, encode func(event interface{}) []byte) {
+	flusher, _ := w.(http.Flusher)
+	limit := len(events)
+	if rule != nil && rule.Truncate {
+		limit = len(events) / 2
+	}
+	delay := s.tokenDelay(len(events))
+	for i, event := range events[:limit] {
+		if i > 0 && delay > 0 {
+			time.Sleep(delay)
+		}
+		data := encode(event)
+		if rule != nil && rule.Malformed && i == 1 {
+			data = data[:len(data)/2]
+			data = append(data, '\n', '\n')
+		}
+		w.Write(data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package keywords
+
+import (
+	"math"
+	"sync"
+)
+
+// Corpus counts in how many of a writer's entries each word appears, for the inverse
+// document frequency weighting of Extract. It is safe for concurrent use. A nil
+// Corpus weights every word equally.
+type Corpus struct {
+	mu        sync.RWMutex
+	documents int
+	frequency map[string]int
+}
+
+func NewCorpus() *Corpus {
+	return &Corpus{frequency: make(map[string]int)}
+}
+
+// Add counts a document. Each word counts once per document.
+func (c *Corpus) Add(text, language string) {
+	words := documentWords(text, language)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.documents++
+	for word := range words {
+		c.frequency[word]++
+	}
+}
+
+// Remove uncounts a document previously added with the same text.
+func (c *Corpus) Remove(text, language string) {
+	words := documentWords(text, language)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.documents > 0 {
+		c.documents--
+	}
+	for word := range words {
+		if c.frequency[word] <= 1 {
+			delete(c.frequency, word)
+		} else {
+			c.frequency[word]--
+		}
+	}
+}
+
+// Documents returns the number of documents counted.
+func (c *Corpus) Documents() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.documents
+}
+
+// IDF returns the smoothed inverse document frequency of a word: 1 for a word in
+// every document, growing as the word gets rarer.
+func (c *Corpus) IDF(word string) float64 {
+	if c == nil {
+		return 1
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.documents == 0 {
+		return 1
+	}
+	return 1 + math.Log(float64(1+c.documents)/float64(1+c.frequency[word]))
+}
+
+func documentWords(text, language string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range Words(text, language) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,217 @@
+// Package keywords extracts key phrases from journal text without a language model.
+// Candidate phrases are found with RAKE (runs of words between stopwords and
+// punctuation) and weighted by how rare their words are across the writer's own
+// entries (TF-IDF), so words someone uses every day rank below the ones that make an
+// entry different.
+package keywords
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Keyword is an extracted phrase with its score relative to the best phrase of the
+// same text, which scores 1.
+type Keyword struct {
+	Term  string
+	Score float64
+}
+
+// Options tune Extract. The zero value extracts five English keywords without
+// corpus weighting.
+type Options struct {
+	Language string  // ISO 639-1 code; see Languages
+	Limit    int     // defaults to 5
+	Corpus   *Corpus // the writer's other entries, for IDF weighting
+}
+
+// maxPhraseWords bounds candidate phrases; longer runs are split.
+const maxPhraseWords = 3
+
+// Extract returns the key phrases of text, best first.
+func Extract(text string, opts Options) []Keyword {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	stop := Stopwords(opts.Language)
+
+	phrases := candidates(text, stop)
+	if len(phrases) == 0 {
+		return nil
+	}
+
+	// RAKE word scores: degree (co-occurrence within phrases) over frequency, which
+	// favours words that appear in longer phrases.
+	freq := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			freq[word]++
+			degree[word] += len(phrase)
+		}
+	}
+
+	type scored struct {
+		words []string
+		score float64
+	}
+	byTerm := make(map[string]*scored)
+	counts := make(map[string]int)
+	for _, phrase := range phrases {
+		term := strings.Join(phrase, " ")
+		counts[term]++
+		if _, ok := byTerm[term]; ok {
+			continue
+		}
+		rake, idf := 0.0, 0.0
+		for _, word := range phrase {
+			rake += float64(degree[word]) / float64(freq[word])
+			idf += opts.Corpus.IDF(word)
+		}
+		byTerm[term] = &scored{words: phrase, score: rake * idf / float64(len(phrase))}
+	}
+	// Repeated phrases gain weight, dampened as term frequency is in TF-IDF.
+	for term, s := range byTerm {
+		s.score *= 1 + math.Log(float64(counts[term]))
+	}
+
+	ranked := make([]string, 0, len(byTerm))
+	for term := range byTerm {
+		ranked = append(ranked, term)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := byTerm[ranked[i]], byTerm[ranked[j]]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return ranked[i] < ranked[j]
+	})
+
+	// Skip phrases whose words are all covered by better ones, so "morning run" and
+	// "run" are not both returned.
+	var keywords []Keyword
+	covered := make(map[string]bool)
+	best := byTerm[ranked[0]].score
+	for _, term := range ranked {
+		s := byTerm[term]
+		redundant := true
+		for _, word := range s.words {
+			if !covered[word] {
+				redundant = false
+			}
+		}
+		if redundant {
+			continue
+		}
+		for _, word := range s.words {
+			covered[word] = true
+		}
+		keywords = append(keywords, Keyword{Term: term, Score: math.Round(s.score/best*1000) / 1000})
+		if len(keywords) == limit {
+			break
+		}
+	}
+	return keywords
+}
+
+// candidates splits text into runs of content words. Stopwords, punctuation and
+// numbers end a run.
+func candidates(text string, stop map[string]bool) [][]string {
+	var phrases [][]string
+	var current []string
+	flush := func() {
+		for len(current) > 0 {
+			n := len(current)
+			if n > maxPhraseWords {
+				n = maxPhraseWords
+			}
+			phrases = append(phrases, current[:n])
+			current = current[n:]
+		}
+		current = nil
+	}
+
+	for _, token := range tokenize(text) {
+		if token.boundary {
+			flush()
+			continue
+		}
+		word := Normalize(token.text)
+		if word == "" || stop[word] || !contentWord(word) {
+			flush()
+			continue
+		}
+		current = append(current, word)
+	}
+	flush()
+	return phrases
+}
+
+type token struct {
+	text     string
+	boundary bool // punctuation that ends a phrase
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	var word strings.Builder
+	emit := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, token{text: word.String()})
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '’' || r == '-':
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			emit()
+		default:
+			emit()
+			tokens = append(tokens, token{boundary: true})
+		}
+	}
+	emit()
+	return tokens
+}
+
+// Normalize lowercases a word or phrase, unifies apostrophes, drops possessive 's
+// and trims surrounding punctuation.
+func Normalize(term string) string {
+	term = strings.ToLower(strings.TrimSpace(term))
+	term = strings.ReplaceAll(term, "’", "'")
+	fields := strings.Fields(term)
+	for i, field := range fields {
+		field = strings.TrimSuffix(field, "'s")
+		fields[i] = strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	}
+	return strings.Join(strings.Fields(strings.Join(fields, " ")), " ")
+}
+
+// contentWord rejects numbers and very short words, which make poor keywords.
+func contentWord(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	// Scripts without spaces between words (Chinese, Japanese) come through as long
+	// runs and are kept; alphabetic words need at least three letters.
+	return letters >= 3
+}
+
+// Words returns the normalized content words of text in order, without stopwords.
+func Words(text, language string) []string {
+	var words []string
+	for _, phrase := range candidates(text, Stopwords(language)) {
+		words = append(words, phrase...)
+	}
+	return words
+}
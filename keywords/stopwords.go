@@ -0,0 +1,85 @@
+package keywords
+
+import "strings"
+
+// stopwordLists holds the words that never start, end or sit inside a key phrase,
+// per ISO 639-1 language code. Besides function words they include the filler of
+// diary writing ("today", "really", "feel") so phrases centre on what happened.
+var stopwordLists = map[string]string{
+	"en": `a about above after again against all also am an and any are aren't as at be
+		because been before being below between both but by can can't cannot could couldn't
+		did didn't do does doesn't doing don't down during each few for from further get
+		gets getting got had hadn't has hasn't have haven't having he he'd he'll he's her
+		here here's hers herself him himself his how how's i i'd i'll i'm i've if in into
+		is isn't it it's its itself just let's like lot me more most much must mustn't my
+		myself no nor not now of off on once one only or other ought our ours ourselves out
+		over own really same she she'd she'll she's should shouldn't so some still such than
+		that that's the their theirs them themselves then there there's these they they'd
+		they'll they're they've thing things this those through to today too under until up
+		very was wasn't we we'd we'll we're we've were weren't what what's when when's where
+		where's which while who who's whom why why's will with won't would wouldn't yes yet
+		you you'd you'll you're you've your yours yourself yourselves
+		bit day feel feeling felt going gonna kind know made make maybe pretty quite said say
+		see seem seemed something think thought tonight want wanted way went yesterday
+		ago every last next time week year years`,
+	"es": `a al algo algunas algunos ante antes como con contra cual cuando de del desde
+		donde durante e el ella ellas ellos en entre era eran es esa esas ese eso esos esta
+		estaba estaban estar estas este esto estos estoy fue fueron fui ha había han has hasta
+		hay he hoy la las le les lo los mas me mi mis mucho muy más mí nada ni no nos nosotros
+		o os otra otro para pero poco por porque que quien qué se sea ser si siento sin sobre
+		solo son soy su sus también tan te tengo tenía ti tiene todo todos tu tus un una uno
+		unos y ya yo`,
+	"fr": `a ai au aujourd'hui aussi autre aux avais avait avec avoir bien c'est ce cela ces
+		cet cette comme dans de des donc du elle elles en encore est et été être eu fait il
+		ils j'ai je journée l'ai la le les leur lui ma mais me mes moi mon même ne ni nos
+		notre nous on ont ou où par pas peu plus pour qu'il que qui sa sans se ses si son sont
+		suis sur ta te tes toi ton tous tout très tu un une vos votre vous y à ça`,
+	"de": `aber alle allem als also am an auch auf aus bei bin bis bist da dann das dass
+		dem den der des die dies diese diesem dieser doch dort du durch ein eine einem einen
+		einer er es etwas für habe haben hat hatte heute ich ihm ihn ihr im in ist ja jetzt
+		kann kein keine man mein meine mich mir mit nach nicht noch nur ob oder schon sehr
+		sich sie sind so um und uns unser von vor war waren was weil wenn wie wieder wir wird
+		zu zum zur über`,
+	"pt": `a ao aos as até com como da das de dela dele do dos e ela ele eles em entre era
+		essa esse esta estava este estou eu foi fui hoje isso isto já lhe mais mas me meu
+		minha muito na nas nem no nos não o os ou para pela pelo por porque quando que se
+		sem ser seu sua são também te tem tenho tinha um uma você à é`,
+	"it": `a ad al alla alle anche ancora avere c'è che chi ci come con cosa da dal dalla
+		dei del della delle di e ed era ero gli ha hanno ho i il in io la le lei lo loro lui
+		ma mi mia mio molto ne nel nella no non oggi per perché più poi quando quella quello
+		questa questo se sei si sono su sua suo sul tra tu tutto un una uno è`,
+	"nl": `aan al als ben bij dan dat de deze die dit doen door een en er had heb hebben
+		heel het hij hem hier hoe ik in is je kan maar me mee meer met mij mijn na naar niet
+		nog nu of om ook op over te tot uit van vandaag veel voor was wat we wel werd wie wij
+		zal ze zich zijn zo`,
+}
+
+var stopwords = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwordLists))
+	for lang, list := range stopwordLists {
+		set := make(map[string]bool)
+		for _, word := range strings.Fields(list) {
+			set[Normalize(word)] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// Languages returns the language codes that have a stopword list.
+func Languages() []string {
+	return []string{"de", "en", "es", "fr", "it", "nl", "pt"}
+}
+
+// Stopwords returns the stopword set for a language, English for languages without a
+// list. Codes are matched on their primary subtag, so "pt-BR" uses Portuguese.
+func Stopwords(language string) map[string]bool {
+	language = strings.ToLower(language)
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if set, ok := stopwords[language]; ok {
+		return set
+	}
+	return stopwords["en"]
+}
@@ -0,0 +1,176 @@
+// Package language detects the language of journal text offline. Non-Latin scripts
+// mostly identify their language on their own (Hangul, kana, Greek...). Latin-script
+// text is scored by its share of each language's stopwords, with a nudge from
+// characters only some languages use, such as ñ or ß.
+package language
+
+import (
+	"strings"
+	"unicode"
+
+	"soulprint-backend/keywords"
+)
+
+// names maps the ISO 639-1 codes Detect can return to their English names.
+var names = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// Detection is the result of Detect. Code is empty when the text is too short or too
+// mixed to tell.
+type Detection struct {
+	Code       string
+	Confidence float64 // 0 to 1
+}
+
+// minScore is the stopword evidence Latin-script text needs before a language is named.
+const minScore = 2
+
+// Detect returns the most likely language of text.
+func Detect(text string) Detection {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			scripts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["cyrillic"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		}
+	}
+	if letters == 0 {
+		return Detection{}
+	}
+
+	script, count := "", 0
+	for name, n := range scripts {
+		if n > count || (n == count && name < script) {
+			script, count = name, n
+		}
+	}
+	share := float64(count) / float64(letters)
+
+	switch script {
+	case "latin":
+		return detectLatin(text)
+	case "kana", "han":
+		// Japanese mixes kanji with kana; Chinese has none.
+		cjk := float64(scripts["kana"]+scripts["han"]) / float64(letters)
+		if scripts["kana"] > 0 {
+			return Detection{Code: "ja", Confidence: cjk}
+		}
+		return Detection{Code: "zh", Confidence: cjk}
+	case "cyrillic":
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return Detection{Code: "uk", Confidence: share}
+		}
+		return Detection{Code: "ru", Confidence: share}
+	}
+	return Detection{Code: script, Confidence: share}
+}
+
+// hints are characters that point to one language.
+var hints = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ã': "pt", 'õ': "pt",
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'œ': "fr", 'ê': "fr", 'è': "fr", 'û': "fr",
+	'ì': "it", 'ò': "it",
+	'ĳ': "nl",
+}
+
+func detectLatin(text string) Detection {
+	lower := strings.ToLower(text)
+	scores := make(map[string]float64)
+	codes := keywords.Languages()
+
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	})
+	for _, word := range words {
+		word = keywords.Normalize(word)
+		// A stopword shared by several languages is split between them.
+		var matches []string
+		for _, code := range codes {
+			if keywords.Stopwords(code)[word] {
+				matches = append(matches, code)
+			}
+		}
+		for _, code := range matches {
+			scores[code] += 1 / float64(len(matches))
+		}
+	}
+	for _, r := range lower {
+		if code, ok := hints[r]; ok {
+			scores[code] += 0.5
+		}
+	}
+
+	best, total := "", 0.0
+	for _, code := range codes {
+		total += scores[code]
+		if scores[code] > scores[best] {
+			best = code
+		}
+	}
+	if best == "" || scores[best] < minScore {
+		return Detection{}
+	}
+	return Detection{Code: best, Confidence: scores[best] / total}
+}
+
+// Normalize lowercases a language tag and reduces it to its primary subtag, so
+// "pt-BR" becomes "pt".
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// Name returns the English name of a language code, or "" if it is not supported.
+func Name(code string) string {
+	return names[Normalize(code)]
+}
+
+// Supported reports whether a language code is one Detect can return.
+func Supported(code string) bool {
+	return Name(code) != ""
+}
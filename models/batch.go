@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReflectionBatch tracks reflections generated in the background for many entries.
+type ReflectionBatch struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	Type        string             `json:"type" bson:"type"`
+	Force       bool               `json:"force,omitempty" bson:"force,omitempty"`
+	Status      string             `json:"status" bson:"status"` // "running", "completed"
+	Total       int                `json:"total" bson:"total"`
+	Succeeded   int                `json:"succeeded" bson:"succeeded"`
+	Cached      int                `json:"cached" bson:"cached"` // succeeded without a model call
+	Failed      int                `json:"failed" bson:"failed"`
+	Items       []BatchItem        `json:"items" bson:"items"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+	HeartbeatAt time.Time          `json:"-" bson:"heartbeat_at"` // refreshed while a server works on the batch
+	CompletedAt *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// BatchItem is the outcome for one entry of a batch.
+type BatchItem struct {
+	EntryID      primitive.ObjectID  `json:"entry_id" bson:"entry_id"`
+	Status       string              `json:"status" bson:"status"` // "pending", "succeeded", "failed"
+	ReflectionID *primitive.ObjectID `json:"reflection_id,omitempty" bson:"reflection_id,omitempty"`
+	Error        string              `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// BatchReflectionRequest selects entries either by ID or by filter.
+type BatchReflectionRequest struct {
+	EntryIDs []string     `json:"entry_ids,omitempty"`
+	Filter   *BatchFilter `json:"filter,omitempty"`
+	Type     string       `json:"type,omitempty"` // defaults to "insight"
+	Force    bool         `json:"force,omitempty"`
+}
+
+type BatchFilter struct {
+	From               string `json:"from,omitempty"` // YYYY-MM-DD, inclusive
+	To                 string `json:"to,omitempty"`   // YYYY-MM-DD, inclusive
+	Tag                string `json:"tag,omitempty"`
+	WithoutReflections bool   `json:"without_reflections,omitempty"` // only entries with no reflection yet
+}
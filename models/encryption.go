@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DataKey is a per-user AES-256 key, stored wrapped (encrypted) by the master key.
+// The newest key that is not retired encrypts new data; older keys are kept so that
+// existing ciphertext stays readable until it has been re-encrypted.
+type DataKey struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	WrappedKey  []byte             `json:"-" bson:"wrapped_key"`
+	MasterKeyID string             `json:"master_key_id" bson:"master_key_id"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	RetiredAt   *time.Time         `json:"retired_at,omitempty" bson:"retired_at,omitempty"`
+}
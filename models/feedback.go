@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReflectionFeedback is a user's rating of a reflection. There is at most one per
+// user and reflection; submitting again replaces it.
+type ReflectionFeedback struct {
+	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	ReflectionID primitive.ObjectID `json:"reflection_id" bson:"reflection_id"`
+	EntryID      primitive.ObjectID `json:"entry_id,omitempty" bson:"entry_id,omitempty"`
+	UserID       string             `json:"user_id" bson:"user_id"`
+	Thumbs       string             `json:"thumbs,omitempty" bson:"thumbs,omitempty"` // "up" or "down"
+	Rating       int                `json:"rating,omitempty" bson:"rating,omitempty"` // 1 to 5
+	Comment      string             `json:"comment,omitempty" bson:"comment,omitempty"`
+	// Copied from the reflection so ratings can be aggregated without a join.
+	Type          string    `json:"type" bson:"type"`
+	PromptVersion string    `json:"prompt_version,omitempty" bson:"prompt_version,omitempty"`
+	Model         string    `json:"model,omitempty" bson:"model,omitempty"`
+	Tone          string    `json:"tone,omitempty" bson:"tone,omitempty"`
+	Length        string    `json:"length,omitempty" bson:"length,omitempty"`
+	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type FeedbackRequest struct {
+	Thumbs  string `json:"thumbs,omitempty"`
+	Rating  int    `json:"rating,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// FeedbackStats aggregates feedback for one combination of the grouped fields.
+type FeedbackStats struct {
+	Group         map[string]string `json:"group"`
+	Count         int               `json:"count"`
+	ThumbsUp      int               `json:"thumbs_up"`
+	ThumbsDown    int               `json:"thumbs_down"`
+	Ratings       int               `json:"ratings"`
+	AverageRating *float64          `json:"average_rating,omitempty"`
+}
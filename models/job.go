@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobRun records one execution of a scheduled background job.
+type JobRun struct {
+	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Job          string             `json:"job" bson:"job"`
+	Owner        string             `json:"owner" bson:"owner"`     // replica that ran the job
+	Trigger      string             `json:"trigger" bson:"trigger"` // "schedule" or "manual"
+	ScheduledFor time.Time          `json:"scheduled_for" bson:"scheduled_for"`
+	StartedAt    time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt   *time.Time         `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	DurationMs   int64              `json:"duration_ms" bson:"duration_ms"`
+	Status       string             `json:"status" bson:"status"` // "running", "succeeded", "failed"
+	Error        string             `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// JobStatus describes a registered job for the admin API.
+type JobStatus struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	NextRun time.Time `json:"next_run"`
+	Running bool      `json:"running"`
+	LastRun *JobRun   `json:"last_run,omitempty"`
+}
@@ -3,29 +3,101 @@ package models
 import (
 	"time"
 
+	"soulprint-backend/utils"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type JournalEntry struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	UserID    string             `json:"user_id" bson:"user_id"`
-	Title     string             `json:"title" bson:"title"`
-	Content   string             `json:"content" bson:"content"`
-	Tags      []string           `json:"tags,omitempty" bson:"tags,omitempty"`
-	Mood      string             `json:"mood,omitempty" bson:"mood,omitempty"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID       primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID   string             `json:"user_id" bson:"user_id"`
+	Title    string             `json:"title" bson:"title"`
+	Content  string             `json:"content" bson:"content"`
+	Envelope *EncryptedEnvelope `json:"envelope,omitempty" bson:"envelope,omitempty"` // set instead of Content in end-to-end encrypted mode
+	Tags     []string           `json:"tags,omitempty" bson:"tags,omitempty"`
+	Mood     string             `json:"mood,omitempty" bson:"mood,omitempty"`
+	Language string             `json:"language,omitempty" bson:"language,omitempty"` // ISO 639-1, detected unless given; empty if unknown
+	// PromptID and TemplateID record the library prompt and entry template the entry
+	// was written with. Answers holds the template's fields; Content is written from it.
+	PromptID   string        `json:"prompt_id,omitempty" bson:"prompt_id,omitempty"`
+	TemplateID string        `json:"template_id,omitempty" bson:"template_id,omitempty"`
+	Answers    []FieldAnswer `json:"answers,omitempty" bson:"answers,omitempty"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at" bson:"updated_at"`
+	DeletedAt  *time.Time    `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	// Keywords are extracted from the title and content on every write, best first.
+	// Entries written before extraction have no keywords field until backfilled.
+	Keywords []Keyword `json:"keywords,omitempty" bson:"keywords"`
+	// Version is bumped on every write and backs the entry's ETag. Entries written
+	// before versioning have version 0.
+	Version int64 `json:"version" bson:"version"`
+	// RevisionCount is the number of stored revisions and numbers the next one.
+	// Entries last edited before the counter existed have none.
+	RevisionCount int `json:"-" bson:"revision_count,omitempty"`
+}
+
+// Keyword is a key phrase of an entry, scored relative to the entry's best phrase (1).
+type Keyword struct {
+	Term  string  `json:"term" bson:"term"`
+	Score float64 `json:"score" bson:"score"`
+}
+
+// EntryRevision is a snapshot of an entry as it was before an update.
+type EntryRevision struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	EntryID    primitive.ObjectID `json:"entry_id" bson:"entry_id"`
+	UserID     string             `json:"user_id" bson:"user_id"`
+	Revision   int                `json:"revision" bson:"revision"` // 1 = the entry as originally created
+	Title      string             `json:"title" bson:"title"`
+	Content    string             `json:"content" bson:"content"`
+	Envelope   *EncryptedEnvelope `json:"envelope,omitempty" bson:"envelope,omitempty"`
+	Tags       []string           `json:"tags,omitempty" bson:"tags,omitempty"`
+	Mood       string             `json:"mood,omitempty" bson:"mood,omitempty"`
+	PromptID   string             `json:"prompt_id,omitempty" bson:"prompt_id,omitempty"`
+	TemplateID string             `json:"template_id,omitempty" bson:"template_id,omitempty"`
+	Answers    []FieldAnswer      `json:"answers,omitempty" bson:"answers,omitempty"`
+	EditedAt   time.Time          `json:"edited_at" bson:"edited_at"`   // when this version was written
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"` // when it was replaced
+}
+
+// RevisionDiff is a word-level comparison between two versions of an entry.
+type RevisionDiff struct {
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Title        []utils.DiffOp `json:"title"`
+	Content      []utils.DiffOp `json:"content"`
+	TagsAdded    []string       `json:"tags_added,omitempty"`
+	TagsRemoved  []string       `json:"tags_removed,omitempty"`
+	MoodFrom     string         `json:"mood_from,omitempty"`
+	MoodTo       string         `json:"mood_to,omitempty"`
+	WordsAdded   int            `json:"words_added"`
+	WordsRemoved int            `json:"words_removed"`
 }
 
 type Reflection struct {
-	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	EntryID     primitive.ObjectID `json:"entry_id" bson:"entry_id"`
-	UserID      string             `json:"user_id" bson:"user_id"`
-	Content     string             `json:"content" bson:"content"`
-	Type        string             `json:"type" bson:"type"` // "insight", "summary", "analysis"
-	Keywords    []string           `json:"keywords,omitempty" bson:"keywords,omitempty"`
-	Sentiment   string             `json:"sentiment,omitempty" bson:"sentiment,omitempty"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	ID            primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	EntryID       primitive.ObjectID   `json:"entry_id,omitempty" bson:"entry_id,omitempty"`
+	EntryIDs      []primitive.ObjectID `json:"entry_ids,omitempty" bson:"entry_ids,omitempty"` // entries covered by a digest
+	UserID        string               `json:"user_id" bson:"user_id"`
+	Content       string               `json:"content" bson:"content"`
+	Type          string               `json:"type" bson:"type"`                         // "insight", "summary", "analysis", "digest", "support"
+	Period        string               `json:"period,omitempty" bson:"period,omitempty"` // "weekly", "monthly", "yearly" (digests only)
+	PeriodStart   *time.Time           `json:"period_start,omitempty" bson:"period_start,omitempty"`
+	PeriodEnd     *time.Time           `json:"period_end,omitempty" bson:"period_end,omitempty"`
+	Keywords      []string             `json:"keywords,omitempty" bson:"keywords,omitempty"`
+	Sentiment     string               `json:"sentiment,omitempty" bson:"sentiment,omitempty"`
+	Language      string               `json:"language,omitempty" bson:"language,omitempty"` // the language the reflection was asked for
+	Safety        *SafetyFlag          `json:"safety,omitempty" bson:"safety,omitempty"`     // set when a supportive response replaced the reflection
+	Model         string               `json:"model,omitempty" bson:"model,omitempty"`
+	PromptVersion string               `json:"prompt_version,omitempty" bson:"prompt_version,omitempty"`
+	Tone          string               `json:"tone,omitempty" bson:"tone,omitempty"`
+	Length        string               `json:"length,omitempty" bson:"length,omitempty"`
+	ParentID      *primitive.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"` // the reflection this one regenerated
+	RootID        *primitive.ObjectID  `json:"root_id,omitempty" bson:"root_id,omitempty"`     // the first version, shared by all regenerations
+	CacheKey      string               `json:"-" bson:"cache_key,omitempty"`                   // hash of the inputs, cleared when the entry's text changes
+	Cached        bool                 `json:"cached,omitempty" bson:"-"`                      // served from the cache
+	CreatedAt     time.Time            `json:"created_at" bson:"created_at"`
+	DeletedAt     *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
 type User struct {
@@ -35,16 +107,111 @@ type User struct {
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 }
 
+// YearReview is the stored "year in review" report for a user.
+type YearReview struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	Year        int                `json:"year" bson:"year"`
+	Stats       YearStats          `json:"stats" bson:"stats"`
+	Highlights  []ReportHighlight  `json:"highlights" bson:"highlights"`
+	Narrative   string             `json:"narrative" bson:"narrative"`
+	Safety      *SafetyFlag        `json:"safety,omitempty" bson:"safety,omitempty"`
+	GeneratedAt time.Time          `json:"generated_at" bson:"generated_at"`
+}
+
+type YearStats struct {
+	TotalEntries    int            `json:"total_entries" bson:"total_entries"`
+	TotalWords      int            `json:"total_words" bson:"total_words"`
+	ActiveDays      int            `json:"active_days" bson:"active_days"`
+	LongestStreak   int            `json:"longest_streak" bson:"longest_streak"`
+	EntriesByMonth  []int          `json:"entries_by_month" bson:"entries_by_month"` // index 0 = January
+	MoodTrend       []MonthMood    `json:"mood_trend" bson:"mood_trend"`
+	TopTags         []TermCount    `json:"top_tags" bson:"top_tags"`
+	TopThemes       []TermCount    `json:"top_themes" bson:"top_themes"`
+	SentimentCounts map[string]int `json:"sentiment_counts" bson:"sentiment_counts"`
+}
+
+type MonthMood struct {
+	Month        string         `json:"month" bson:"month"` // "2024-01"
+	Entries      int            `json:"entries" bson:"entries"`
+	Moods        map[string]int `json:"moods,omitempty" bson:"moods,omitempty"`
+	TopMood      string         `json:"top_mood,omitempty" bson:"top_mood,omitempty"`
+	AverageScore float64        `json:"average_score" bson:"average_score"` // -1 (negative) to 1 (positive)
+}
+
+type TermCount struct {
+	Term  string `json:"term" bson:"term"`
+	Count int    `json:"count" bson:"count"`
+}
+
+type ReportHighlight struct {
+	EntryID primitive.ObjectID `json:"entry_id" bson:"entry_id"`
+	Title   string             `json:"title" bson:"title"`
+	Date    time.Time          `json:"date" bson:"date"`
+	Reason  string             `json:"reason" bson:"reason"`
+	Excerpt string             `json:"excerpt" bson:"excerpt"`
+}
+
+// Topic is a theme running through a user's entries, found by clustering them.
+// Topics keep their ID across refreshes as long as they keep most of their entries.
+type Topic struct {
+	ID        primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    string               `json:"user_id" bson:"user_id"`
+	Label     string               `json:"label" bson:"label"`
+	Terms     []string             `json:"terms" bson:"terms"` // most characteristic words, best first
+	Size      int                  `json:"size" bson:"size"`
+	EntryIDs  []primitive.ObjectID `json:"-" bson:"entry_ids"`
+	History   []TopicSize          `json:"history" bson:"history"` // size at each refresh, oldest first
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+	RetiredAt *time.Time           `json:"retired_at,omitempty" bson:"retired_at,omitempty"` // set once a refresh no longer finds the topic
+}
+
+type TopicSize struct {
+	Date time.Time `json:"date" bson:"date"`
+	Size int       `json:"size" bson:"size"`
+}
+
+// TrashedEntry is an entry in the trash together with the time it will be purged.
+type TrashedEntry struct {
+	JournalEntry `bson:",inline"`
+	PurgeAt      time.Time `json:"purge_at" bson:"-"`
+}
+
 type CreateJournalRequest struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags,omitempty"`
-	Mood    string   `json:"mood,omitempty"`
+	Title      string             `json:"title"`
+	Content    string             `json:"content"`
+	Envelope   *EncryptedEnvelope `json:"envelope,omitempty"` // end-to-end encrypted mode only, replaces Content
+	Tags       []string           `json:"tags,omitempty"`
+	Mood       string             `json:"mood,omitempty"`
+	Language   string             `json:"language,omitempty"`    // ISO 639-1; detected from the text when omitted
+	PromptID   string             `json:"prompt_id,omitempty"`   // the library prompt being answered
+	TemplateID string             `json:"template_id,omitempty"` // built-in or user template
+	// Answers fill in the template's fields. When given, Content is written from them
+	// and the title defaults to the template's name.
+	Answers []FieldAnswer `json:"answers,omitempty"`
 }
 
 type ReflectionRequest struct {
 	EntryID string `json:"entry_id"`
 	Type    string `json:"type,omitempty"` // defaults to "insight"
+	// Content is a decrypted copy of an end-to-end encrypted entry. It is used for this
+	// request only and never stored.
+	Content string `json:"content,omitempty"`
+	Force   bool   `json:"force,omitempty"`  // generate a new reflection even if a cached one matches
+	Tone    string `json:"tone,omitempty"`   // see utils.ReflectionTones
+	Length  string `json:"length,omitempty"` // "short", "medium" or "long"
+}
+
+// RegenerateRequest asks for a new version of a reflection in a different style.
+type RegenerateRequest struct {
+	Tone   string `json:"tone,omitempty"`
+	Length string `json:"length,omitempty"`
+}
+
+type DigestRequest struct {
+	Period string `json:"period"`         // "weekly", "monthly" or "yearly"
+	Date   string `json:"date,omitempty"` // YYYY-MM-DD inside the window, defaults to the last completed period
 }
 
 type CreateUserRequest struct {
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Prompt is a writing prompt from the built-in library.
+type Prompt struct {
+	ID       string `json:"id"`
+	Category string `json:"category"` // "daily", "gratitude", "cbt", "morning_pages" or "theme"
+	Text     string `json:"text"`
+	// Template is the ID of the entry template the prompt is meant to be answered
+	// with, if any.
+	Template string `json:"template,omitempty"`
+}
+
+// EntryTemplate structures an entry as a list of fields to fill in. Built-in templates
+// have readable IDs such as "thought-record"; user templates get generated ones.
+type EntryTemplate struct {
+	ID          string          `json:"id" bson:"_id"`
+	UserID      string          `json:"user_id,omitempty" bson:"user_id"`
+	Name        string          `json:"name" bson:"name"`
+	Description string          `json:"description,omitempty" bson:"description,omitempty"`
+	Fields      []TemplateField `json:"fields" bson:"fields"`
+	BuiltIn     bool            `json:"built_in" bson:"-"`
+	CreatedAt   time.Time       `json:"created_at,omitempty" bson:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at,omitempty" bson:"updated_at"`
+}
+
+// TemplateField is one question of an entry template.
+type TemplateField struct {
+	Key      string   `json:"key" bson:"key"` // lowercase letters, digits and underscores
+	Label    string   `json:"label" bson:"label"`
+	Type     string   `json:"type" bson:"type"`                     // "text", "long_text", "scale" or "choice"
+	Hint     string   `json:"hint,omitempty" bson:"hint,omitempty"` // shown as placeholder text
+	Required bool     `json:"required,omitempty" bson:"required,omitempty"`
+	Min      int      `json:"min,omitempty" bson:"min,omitempty"`         // scale only
+	Max      int      `json:"max,omitempty" bson:"max,omitempty"`         // scale only
+	Options  []string `json:"options,omitempty" bson:"options,omitempty"` // choice only
+}
+
+// FieldAnswer is the answer to one template field. Scale answers are the number as
+// text.
+type FieldAnswer struct {
+	Key   string `json:"key" bson:"key"`
+	Value string `json:"value" bson:"value"`
+}
+
+type TemplateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Fields      []TemplateField `json:"fields"`
+}
+
+// PromptSuggestion is the prompt suggested for a user's next entry, with the reason
+// it was picked.
+type PromptSuggestion struct {
+	Prompt   Prompt         `json:"prompt"`
+	Template *EntryTemplate `json:"template,omitempty"` // the template the prompt pairs with
+	Reason   string         `json:"reason"`
+	Theme    string         `json:"theme,omitempty"` // the recent theme a "theme" prompt is about
+}
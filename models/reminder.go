@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReminderSchedule tells the reminder job when and how to nudge a user to write.
+type ReminderSchedule struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID     string             `json:"user_id" bson:"user_id"`
+	Enabled    bool               `json:"enabled" bson:"enabled"`
+	TimeOfDay  string             `json:"time_of_day" bson:"time_of_day"`               // "HH:MM" in Timezone
+	Weekdays   []int              `json:"weekdays,omitempty" bson:"weekdays,omitempty"` // 0 = Sunday; empty means every day
+	Timezone   string             `json:"timezone" bson:"timezone"`                     // IANA name, e.g. "Europe/Berlin"
+	Channel    string             `json:"channel" bson:"channel"`                       // "email", "webhook", "file", "noop"
+	Target     string             `json:"target,omitempty" bson:"target,omitempty"`     // email address or webhook URL
+	LastSentAt *time.Time         `json:"last_sent_at,omitempty" bson:"last_sent_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type ReminderRequest struct {
+	Enabled   *bool  `json:"enabled,omitempty"` // defaults to true
+	TimeOfDay string `json:"time_of_day"`
+	Weekdays  []int  `json:"weekdays,omitempty"`
+	Timezone  string `json:"timezone,omitempty"` // defaults to "UTC"
+	Channel   string `json:"channel"`
+	Target    string `json:"target,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SafetyFlag is the outcome of the safety check run before a reflection is generated.
+// It never includes the text that triggered it.
+type SafetyFlag struct {
+	Level      string   `json:"level" bson:"level"` // "concern" or "high"
+	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"`
+	Source     string   `json:"source" bson:"source"` // "lexicon" or "model"
+}
+
+// SafetyEvent records that an entry was flagged, for follow-up and auditing.
+type SafetyEvent struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID     string             `json:"user_id" bson:"user_id"`
+	EntryID    primitive.ObjectID `json:"entry_id,omitempty" bson:"entry_id,omitempty"`
+	SafetyFlag `bson:",inline"`
+	Context    string    `json:"context" bson:"context"` // "reflection", "digest" or "report"
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserSettings holds per-user preferences. Users without a stored document get the
+// zero value.
+type UserSettings struct {
+	ID     primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	UserID string             `json:"user_id" bson:"user_id"`
+	// E2EEnabled switches the journal to end-to-end encrypted mode: entry content is
+	// only accepted as client-encrypted envelopes and the server cannot read it.
+	E2EEnabled bool `json:"e2e_enabled" bson:"e2e_enabled"`
+	// RedactPII replaces personal data with placeholders before entry text is sent to
+	// an external AI provider. Unset means on.
+	RedactPII *bool `json:"redact_pii" bson:"redact_pii,omitempty"`
+	// SensitiveNames are redacted in addition to the built-in patterns.
+	SensitiveNames []string `json:"sensitive_names" bson:"sensitive_names,omitempty"`
+	// ReflectionLanguage is the ISO 639-1 code reflections are written in. Empty means
+	// each reflection follows its entry's language.
+	ReflectionLanguage string    `json:"reflection_language" bson:"reflection_language,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// SettingsRequest updates settings; omitted fields are left unchanged.
+type SettingsRequest struct {
+	E2EEnabled         *bool     `json:"e2e_enabled,omitempty"`
+	RedactPII          *bool     `json:"redact_pii,omitempty"`
+	SensitiveNames     *[]string `json:"sensitive_names,omitempty"`
+	ReflectionLanguage *string   `json:"reflection_language,omitempty"` // "" follows each entry
+}
+
+// EncryptedEnvelope is entry content encrypted by the client. The server stores it
+// as is and never sees the key.
+type EncryptedEnvelope struct {
+	Ciphertext string `json:"ciphertext" bson:"ciphertext"` // base64
+	Nonce      string `json:"nonce" bson:"nonce"`           // base64
+	KeyID      string `json:"key_id" bson:"key_id"`
+	Algorithm  string `json:"algorithm" bson:"algorithm"` // "AES-256-GCM" or "XChaCha20-Poly1305"
+}
+
+// KeyBackup is an opaque, client-encrypted copy of a journal key (for example wrapped
+// with a passphrase-derived key) that the client can fetch again on a new device.
+type KeyBackup struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	KeyID     string             `json:"key_id" bson:"key_id"`
+	Blob      string             `json:"blob" bson:"blob"`                             // base64
+	Metadata  map[string]string  `json:"metadata,omitempty" bson:"metadata,omitempty"` // e.g. KDF parameters
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type KeyBackupRequest struct {
+	Blob     string            `json:"blob"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UsageRecord is the token usage and estimated cost of one AI model call.
+type UsageRecord struct {
+	ID               primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID           string             `json:"user_id" bson:"user_id"`
+	Operation        string             `json:"operation" bson:"operation"` // "reflection", "keywords", "summary", "digest", "safety"
+	Model            string             `json:"model" bson:"model"`
+	PromptTokens     int                `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int                `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int                `json:"total_tokens" bson:"total_tokens"`
+	CostUSD          float64            `json:"cost_usd" bson:"cost_usd"`
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// UsageTotals sums usage records.
+type UsageTotals struct {
+	Calls            int     `json:"calls" bson:"calls"`
+	PromptTokens     int     `json:"prompt_tokens" bson:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens" bson:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens" bson:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd" bson:"cost_usd"`
+}
+
+// UsageGroup is the usage for one user, model, operation or day.
+type UsageGroup struct {
+	Key         string `json:"key" bson:"_id"`
+	UsageTotals `bson:",inline"`
+}
+
+// UsageBudget is a token budget and how much of it is left.
+type UsageBudget struct {
+	Limit     int       `json:"limit"` // 0 = unlimited
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining,omitempty"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// UserUsage is a user's usage for the current day and month.
+type UserUsage struct {
+	UserID        string       `json:"user_id"`
+	Today         UsageTotals  `json:"today"`
+	Month         UsageTotals  `json:"month"`
+	DailyBudget   UsageBudget  `json:"daily_budget"`
+	MonthlyBudget UsageBudget  `json:"monthly_budget"`
+	ByOperation   []UsageGroup `json:"by_operation"` // this month
+	ByModel       []UsageGroup `json:"by_model"`     // this month
+}
+
+// GlobalUsage is the usage across all users in [From, To).
+type GlobalUsage struct {
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Totals  UsageTotals  `json:"totals"`
+	ByUser  []UsageGroup `json:"by_user"`
+	ByModel []UsageGroup `json:"by_model"`
+	ByDay   []UsageGroup `json:"by_day"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookSubscription is a user-registered endpoint that receives journal events.
+type WebhookSubscription struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	URL         string             `json:"url" bson:"url"`
+	Secret      string             `json:"secret,omitempty" bson:"secret"` // only returned when the subscription is created
+	Events      []string           `json:"events" bson:"events"`           // event names, or "*" for all
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Active      bool               `json:"active" bson:"active"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// WebhookDelivery logs one event sent (or being sent) to a subscription.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SubscriptionID primitive.ObjectID `json:"subscription_id" bson:"subscription_id"`
+	UserID         string             `json:"user_id" bson:"user_id"`
+	Event          string             `json:"event" bson:"event"`
+	Payload        string             `json:"payload" bson:"payload"`
+	Status         string             `json:"status" bson:"status"` // "pending", "succeeded", "failed"
+	Attempts       int                `json:"attempts" bson:"attempts"`
+	ResponseStatus int                `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	Error          string             `json:"error,omitempty" bson:"error,omitempty"`
+	NextAttemptAt  *time.Time         `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	LastAttemptAt  *time.Time         `json:"last_attempt_at,omitempty" bson:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time         `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+
+	// EntryIDs are the entries the payload carries, so that the delivery can be
+	// purged with them.
+	EntryIDs []primitive.ObjectID `json:"-" bson:"entry_ids,omitempty"`
+}
+
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"` // defaults to true
+}
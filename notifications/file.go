@@ -0,0 +1,50 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileChannel appends notifications as JSON lines to a local file. It is meant for
+// development and tests, where inspecting what would have been sent is enough.
+type FileChannel struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileChannel(path string) *FileChannel {
+	return &FileChannel{path: path}
+}
+
+func (fc *FileChannel) Name() string { return "file" }
+
+func (fc *FileChannel) Send(ctx context.Context, n Notification) error {
+	line, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	f, err := os.OpenFile(fc.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
+
+// NoopChannel accepts and discards every notification.
+type NoopChannel struct{}
+
+func (NoopChannel) Name() string { return "noop" }
+
+func (NoopChannel) Send(ctx context.Context, n Notification) error { return nil }
@@ -0,0 +1,78 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Notification is a message delivered to a user through a Channel.
+type Notification struct {
+	UserID    string                 `json:"user_id"`
+	To        string                 `json:"to,omitempty"` // email address, webhook URL, ... depending on the channel
+	Subject   string                 `json:"subject"`
+	Body      string                 `json:"body"`
+	Kind      string                 `json:"kind"` // e.g. "reminder"
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Channel delivers notifications over one transport.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// Dispatcher routes notifications to registered channels by name.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	channels map[string]Channel
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		channels: make(map[string]Channel),
+	}
+}
+
+func (d *Dispatcher) Register(channel Channel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.channels[channel.Name()] = channel
+}
+
+// Has reports whether a channel is available.
+func (d *Dispatcher) Has(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.channels[name]
+	return ok
+}
+
+// Channels lists the names of the registered channels.
+func (d *Dispatcher) Channels() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	names := make([]string, 0, len(d.channels))
+	for name := range d.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Dispatcher) Send(ctx context.Context, channelName string, n Notification) error {
+	d.mu.RLock()
+	channel, ok := d.channels[channelName]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("notification channel %q not configured", channelName)
+	}
+
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	return channel.Send(ctx, n)
+}
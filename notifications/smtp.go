@@ -0,0 +1,58 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPChannel sends notifications as plain-text email.
+type SMTPChannel struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPChannel(host, port, username, password, from string) *SMTPChannel {
+	return &SMTPChannel{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (sc *SMTPChannel) Name() string { return "email" }
+
+func (sc *SMTPChannel) Send(ctx context.Context, n Notification) error {
+	if n.To == "" {
+		return fmt.Errorf("email notification requires a recipient")
+	}
+	if strings.ContainsAny(n.To, "\r\n") || strings.ContainsAny(n.Subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+
+	var auth smtp.Auth
+	if sc.username != "" {
+		auth = smtp.PlainAuth("", sc.username, sc.password, sc.host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + sc.from,
+		"To: " + n.To,
+		"Subject: " + n.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		n.Body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(sc.host+":"+sc.port, auth, sc.from, []string{n.To}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
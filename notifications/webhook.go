@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookChannel POSTs notifications as JSON to the URL in Notification.To.
+type WebhookChannel struct {
+	httpClient *http.Client
+}
+
+// NewWebhookChannel sends notifications with httpClient. Targets are chosen by users,
+// so the client should refuse internal addresses like the one webhook subscriptions
+// are delivered with.
+func NewWebhookChannel(httpClient *http.Client) *WebhookChannel {
+	return &WebhookChannel{
+		httpClient: httpClient,
+	}
+}
+
+func (wc *WebhookChannel) Name() string { return "webhook" }
+
+func (wc *WebhookChannel) Send(ctx context.Context, n Notification) error {
+	if n.To == "" {
+		return fmt.Errorf("webhook notification requires a URL")
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.To, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package promptguard
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// systemPrompt stands in for the reflection system prompt when checking outputs.
+const systemPrompt = "You are a thoughtful journal reflection assistant. Provide insightful, empathetic, and constructive reflections on journal entries. " +
+	DataPolicy
+
+// corpus holds adversarial and benign journal entries and model outputs.
+type corpus struct {
+	Entries []struct {
+		Name       string `json:"name"`
+		Content    string `json:"content"`
+		Suspicious bool   `json:"suspicious"`
+	} `json:"entries"`
+	Outputs []struct {
+		Name     string `json:"name"`
+		Output   string `json:"output"`
+		Rejected bool   `json:"rejected"`
+	} `json:"outputs"`
+}
+
+func loadCorpus(t *testing.T) corpus {
+	t.Helper()
+	data, err := os.ReadFile("testdata/injection_corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c corpus
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("invalid corpus: %v", err)
+	}
+	return c
+}
+
+func TestDetect(t *testing.T) {
+	for _, entry := range loadCorpus(t).Entries {
+		t.Run(entry.Name, func(t *testing.T) {
+			detection := Detect(entry.Content)
+			if detection.Suspicious != entry.Suspicious {
+				t.Errorf("suspicious = %v, want %v (signals %v)", detection.Suspicious, entry.Suspicious, detection.Signals)
+			}
+		})
+	}
+}
+
+func TestDelimit(t *testing.T) {
+	// Whatever the detector says, the delimited text must not be able to close or
+	// reopen the entry tags.
+	for _, entry := range loadCorpus(t).Entries {
+		t.Run(entry.Name, func(t *testing.T) {
+			delimited := Delimit(entry.Content)
+			if strings.Count(delimited, "<"+EntryTag+">") != 1 || strings.Count(delimited, "</"+EntryTag+">") != 1 {
+				t.Errorf("delimiter escaped: %q", delimited)
+			}
+		})
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	for _, output := range loadCorpus(t).Outputs {
+		t.Run(output.Name, func(t *testing.T) {
+			err := ValidateOutput(output.Output, systemPrompt)
+			if (err != nil) != output.Rejected {
+				t.Errorf("rejected = %v, want %v (err %v)", err != nil, output.Rejected, err)
+			}
+		})
+	}
+}
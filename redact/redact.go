@@ -0,0 +1,180 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of personal data the redactor detects, in the order they are applied. Cards
+// go before phones so that a card number is not mistaken for one.
+const (
+	KindEmail   = "EMAIL"
+	KindCard    = "CARD"
+	KindPhone   = "PHONE"
+	KindAddress = "ADDRESS"
+	KindName    = "NAME"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}(?:[ .\-]?\d{2,4}){1,4}`)
+
+	streetSuffixes = []string{
+		"street", "st", "avenue", "ave", "road", "rd", "boulevard", "blvd", "lane", "ln",
+		"drive", "dr", "court", "ct", "way", "place", "pl", "terrace", "ter", "square", "sq",
+		"highway", "hwy", "parkway", "pkwy", "crescent", "close", "circle", "cir",
+	}
+	// A house number followed by capitalized words and a street suffix: "221B Baker Street".
+	addressPattern = regexp.MustCompile(`\b\d{1,6}[A-Za-z]?\s+(?:[A-Z][\w'.\-]*\s+){1,4}(?i:` +
+		strings.Join(streetSuffixes, "|") + `)\b\.?(?:,?\s+(?i:apt|apartment|suite|unit|#)\.?\s*\w+)?`)
+
+	datePattern = regexp.MustCompile(`^(?:\d{4}[./\-]\d{1,2}[./\-]\d{1,2}|\d{1,2}[./\-]\d{1,2}[./\-]\d{2,4})$`)
+
+	placeholderPattern = regexp.MustCompile(`\[(` + strings.Join([]string{KindEmail, KindCard, KindPhone, KindAddress, KindName}, "|") + `)_(\d+)\]`)
+)
+
+// minPhoneDigits avoids treating dates, times and other short numbers as phone numbers.
+const minPhoneDigits = 7
+
+// Redactor replaces personal data with placeholders such as [NAME_1] and puts the
+// original values back afterwards. The same value always gets the same placeholder
+// within one Redactor, so text redacted in several calls (e.g. digest chunks) stays
+// consistent. A nil *Redactor leaves text unchanged.
+type Redactor struct {
+	names        *regexp.Regexp
+	placeholders map[string]string // original value -> placeholder
+	originals    map[string]string // placeholder -> original value
+	counts       map[string]int
+}
+
+// New returns a redactor that, besides the built-in patterns, redacts the given
+// names (matched case-insensitively as whole words).
+func New(names []string) *Redactor {
+	r := &Redactor{
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counts:       make(map[string]int),
+	}
+
+	var quoted []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			quoted = append(quoted, regexp.QuoteMeta(name))
+		}
+	}
+	if len(quoted) > 0 {
+		// Longest first so "Anna Maria" wins over "Anna".
+		sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+		r.names = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return r
+}
+
+// Redact replaces detected personal data in text with placeholders.
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	text = r.replace(text, KindEmail, emailPattern, nil)
+	text = r.replace(text, KindCard, cardPattern, luhnValid)
+	text = r.replace(text, KindPhone, phonePattern, looksLikePhone)
+	text = r.replace(text, KindAddress, addressPattern, nil)
+	if r.names != nil {
+		text = r.replace(text, KindName, r.names, nil)
+	}
+	return text
+}
+
+// Restore puts the original values back in place of placeholders. Placeholders the
+// redactor did not hand out are left alone.
+func (r *Redactor) Restore(text string) string {
+	if r == nil || len(r.originals) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := r.originals[placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+// Count reports how many distinct values have been redacted.
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.originals)
+}
+
+func (r *Redactor) replace(text, kind string, pattern *regexp.Regexp, valid func(string) bool) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		// Never redact inside a placeholder handed out by an earlier pass.
+		if placeholderPattern.MatchString(match) {
+			return match
+		}
+		trimmed := strings.TrimSpace(match)
+		if valid != nil && !valid(trimmed) {
+			return match
+		}
+		return strings.Replace(match, trimmed, r.placeholder(kind, trimmed), 1)
+	})
+}
+
+func (r *Redactor) placeholder(kind, value string) string {
+	key := kind + "\x00" + value
+	if placeholder, ok := r.placeholders[key]; ok {
+		return placeholder
+	}
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, r.counts[kind])
+	r.placeholders[key] = placeholder
+	r.originals[placeholder] = value
+	return placeholder
+}
+
+func digits(value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid checks a card number's checksum, which rules out most other long numbers.
+func luhnValid(value string) bool {
+	number := digits(value)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func looksLikePhone(value string) bool {
+	n := len(digits(value))
+	if n < minPhoneDigits || n > 15 || datePattern.MatchString(value) {
+		return false
+	}
+	// Bare digit runs without separators or a leading + are more often amounts, years
+	// or IDs than phone numbers.
+	return strings.HasPrefix(value, "+") || strings.ContainsAny(value, " .-()")
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(journalController *controllers.JournalController, reflectionController *controllers.ReflectionController) *mux.Router {
+func NewRouter(journalController *controllers.JournalController, reflectionController *controllers.ReflectionController, digestController *controllers.DigestController, reportController *controllers.ReportController, adminController *controllers.AdminController, reminderController *controllers.ReminderController, webhookController *controllers.WebhookController, settingsController *controllers.SettingsController, usageController *controllers.UsageController, topicController *controllers.TopicController, promptController *controllers.PromptController) *mux.Router {
 	router := mux.NewRouter()
 
 	// Add CORS middleware
@@ -25,13 +25,83 @@ func NewRouter(journalController *controllers.JournalController, reflectionContr
 	api.HandleFunc("/entries", journalController.GetEntries).Methods("GET")
 	api.HandleFunc("/entries/{id}", journalController.GetEntry).Methods("GET")
 	api.HandleFunc("/entries/{id}", journalController.UpdateEntry).Methods("PUT")
+	api.HandleFunc("/entries/{id}", journalController.PatchEntry).Methods("PATCH")
 	api.HandleFunc("/entries/{id}", journalController.DeleteEntry).Methods("DELETE")
+	api.HandleFunc("/entries/{id}/revisions", journalController.GetRevisions).Methods("GET")
+	api.HandleFunc("/entries/{id}/revisions/{revision}", journalController.GetRevision).Methods("GET")
+	api.HandleFunc("/entries/{id}/revisions/{revision}/restore", journalController.RestoreRevision).Methods("POST")
+	api.HandleFunc("/entries/{id}/diff", journalController.DiffRevisions).Methods("GET")
+
+	// Trash routes
+	api.HandleFunc("/trash", journalController.GetTrash).Methods("GET")
+	api.HandleFunc("/trash/{id}/restore", journalController.RestoreEntry).Methods("POST")
+	api.HandleFunc("/trash/{id}", journalController.PurgeEntry).Methods("DELETE")
 
 	// AI reflection routes
 	api.HandleFunc("/reflect", reflectionController.GenerateReflection).Methods("POST")
+	api.HandleFunc("/reflect/batch", reflectionController.CreateBatch).Methods("POST")
+	api.HandleFunc("/reflect/batch/{id}", reflectionController.GetBatch).Methods("GET")
 	api.HandleFunc("/insights", reflectionController.GetInsights).Methods("GET")
 	api.HandleFunc("/reflections", reflectionController.GetReflections).Methods("GET")
 	api.HandleFunc("/entries/{id}/reflections", reflectionController.GetReflectionsByEntry).Methods("GET")
+	api.HandleFunc("/reflections/{id}/regenerate", reflectionController.RegenerateReflection).Methods("POST")
+	api.HandleFunc("/reflections/{id}/versions", reflectionController.GetReflectionVersions).Methods("GET")
+	api.HandleFunc("/reflections/{id}/feedback", reflectionController.SubmitFeedback).Methods("POST")
+	api.HandleFunc("/reflections/{id}/feedback", reflectionController.GetFeedback).Methods("GET")
+
+	// Digest reflection routes
+	api.HandleFunc("/digests", digestController.GenerateDigest).Methods("POST")
+	api.HandleFunc("/digests", digestController.GetDigests).Methods("GET")
+
+	// Report routes
+	api.HandleFunc("/reports/year/{year}", reportController.GetYearReview).Methods("GET")
+
+	// Topic routes
+	api.HandleFunc("/topics", topicController.GetTopics).Methods("GET")
+	api.HandleFunc("/topics/refresh", topicController.RefreshTopics).Methods("POST")
+	api.HandleFunc("/topics/{id}/entries", topicController.GetTopicEntries).Methods("GET")
+
+	// Prompt and template routes
+	api.HandleFunc("/prompts", promptController.GetPrompts).Methods("GET")
+	api.HandleFunc("/prompts/suggest", promptController.SuggestPrompt).Methods("GET")
+	api.HandleFunc("/templates", promptController.GetTemplates).Methods("GET")
+	api.HandleFunc("/templates", promptController.CreateTemplate).Methods("POST")
+	api.HandleFunc("/templates/{id}", promptController.GetTemplate).Methods("GET")
+	api.HandleFunc("/templates/{id}", promptController.UpdateTemplate).Methods("PUT")
+	api.HandleFunc("/templates/{id}", promptController.DeleteTemplate).Methods("DELETE")
+
+	// Reminder routes
+	api.HandleFunc("/reminders", reminderController.CreateReminder).Methods("POST")
+	api.HandleFunc("/reminders", reminderController.GetReminders).Methods("GET")
+	api.HandleFunc("/reminders/{id}", reminderController.UpdateReminder).Methods("PUT")
+	api.HandleFunc("/reminders/{id}", reminderController.DeleteReminder).Methods("DELETE")
+	api.HandleFunc("/reminders/{id}/test", reminderController.TestReminder).Methods("POST")
+
+	// Webhook routes
+	api.HandleFunc("/webhooks", webhookController.CreateWebhook).Methods("POST")
+	api.HandleFunc("/webhooks", webhookController.GetWebhooks).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", webhookController.UpdateWebhook).Methods("PUT")
+	api.HandleFunc("/webhooks/{id}", webhookController.DeleteWebhook).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id}/deliveries", webhookController.GetDeliveries).Methods("GET")
+	api.HandleFunc("/webhooks/{id}/ping", webhookController.PingWebhook).Methods("POST")
+
+	// Settings and end-to-end key backup routes
+	api.HandleFunc("/settings", settingsController.GetSettings).Methods("GET")
+	api.HandleFunc("/settings", settingsController.UpdateSettings).Methods("PUT")
+	api.HandleFunc("/keys/backups", settingsController.GetKeyBackups).Methods("GET")
+	api.HandleFunc("/keys/backups/{keyId}", settingsController.GetKeyBackup).Methods("GET")
+	api.HandleFunc("/keys/backups/{keyId}", settingsController.PutKeyBackup).Methods("PUT")
+	api.HandleFunc("/keys/backups/{keyId}", settingsController.DeleteKeyBackup).Methods("DELETE")
+
+	// AI usage routes
+	api.HandleFunc("/usage", usageController.GetUsage).Methods("GET")
+
+	// Admin routes
+	api.HandleFunc("/admin/jobs", adminController.GetJobs).Methods("GET")
+	api.HandleFunc("/admin/jobs/{name}/runs", adminController.GetJobRuns).Methods("GET")
+	api.HandleFunc("/admin/jobs/{name}/run", adminController.RunJob).Methods("POST")
+	api.HandleFunc("/admin/usage", usageController.GetGlobalUsage).Methods("GET")
+	api.HandleFunc("/admin/feedback", reflectionController.GetFeedbackStats).Methods("GET")
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -54,8 +124,9 @@ func NewRouter(journalController *controllers.JournalController, reflectionContr
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, Location")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
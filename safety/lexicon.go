@@ -0,0 +1,100 @@
+package safety
+
+import (
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	pattern  *regexp.Regexp
+	level    Level
+	category string
+	// negatable rules are single terms that a preceding "not" or "never" can soften,
+	// as in "I'm not suicidal". Phrases like "don't want to live" carry their own
+	// negation and are never softened.
+	negatable bool
+}
+
+func newRule(pattern string, level Level, category string, negatable bool) rule {
+	return rule{regexp.MustCompile(`\b(?:` + pattern + `)\b`), level, category, negatable}
+}
+
+var rules = []rule{
+	newRule(`suicid(?:e|al)|kill(?:s|ed|ing)? myself`, LevelHigh, CategorySuicidalIdeation, true),
+	newRule(`end(?:s|ed|ing)? (?:it all|my life|my own life)|(?:take|took|taking) my (?:own )?life|(?:hang(?:s|ed|ing)?|shoot(?:s|ing)?|shot|drown(?:s|ed|ing)?) myself`, LevelHigh, CategorySuicidalIdeation, true),
+	newRule(`want(?:ed)? to die|wish(?:ed)? i (?:was|were) dead|better off dead|better off without me`, LevelHigh, CategorySuicidalIdeation, false),
+	newRule(`(?:do not|don't|dont|never|no longer) (?:want to|wanna) (?:live|be alive|exist|wake up)|no (?:reason|point) (?:to|in) (?:live|living|going on)`, LevelHigh, CategorySuicidalIdeation, false),
+	newRule(`suicide note|goodbye (?:letter|note)s?|overdos(?:e|ed|ing)`, LevelHigh, CategorySuicidalIdeation, false),
+	newRule(`(?:cut|cutting|burn|burning|hurt|hurting|harm|harming) myself|self[- ]?harm(?:ing)?`, LevelHigh, CategorySelfHarm, true),
+	newRule(`hopeless|worthless`, LevelConcern, CategoryHopelessness, true),
+	newRule(`(?:can't|cannot|cant) (?:go on|do this any ?more|take (?:it|this) any ?more)`, LevelConcern, CategoryHopelessness, false),
+	newRule(`(?:nobody|no one|noone) would (?:even |really )?(?:miss|notice|care)|(?:a|such a) burden (?:to|on) (?:everyone|everybody|them|my family)`, LevelConcern, CategoryHopelessness, false),
+	newRule(`want to disappear|no way out`, LevelConcern, CategoryHopelessness, false),
+}
+
+var (
+	negators = map[string]bool{
+		"not": true, "never": true, "no": true, "isn't": true, "wasn't": true,
+		"aren't": true, "don't": true, "didn't": true, "won't": true, "wouldn't": true,
+	}
+	wordPattern = regexp.MustCompile(`[a-z']+`)
+)
+
+// negationWindow is how many words before a match are searched for a negator.
+const negationWindow = 3
+
+// LexiconClassifier is an offline, rule based classifier. It errs on the side of
+// flagging: a false positive costs a supportive message instead of a reflection,
+// a false negative could cost far more. A negated term ("not suicidal") is softened
+// by one level rather than ignored.
+type LexiconClassifier struct{}
+
+func NewLexiconClassifier() *LexiconClassifier {
+	return &LexiconClassifier{}
+}
+
+func (lc *LexiconClassifier) Classify(text string) (Assessment, error) {
+	text = normalize(text)
+	result := Assessment{Source: SourceLexicon}
+	categories := make(map[string]bool)
+
+	for _, r := range rules {
+		for _, loc := range r.pattern.FindAllStringIndex(text, -1) {
+			level := r.level
+			if r.negatable && negated(text[:loc[0]]) {
+				level--
+			}
+			if level == LevelNone {
+				continue
+			}
+			if level > result.Level {
+				result.Level = level
+			}
+			categories[r.category] = true
+		}
+	}
+	if result.Flagged() {
+		result.Categories = sortedKeys(categories)
+	}
+	return result, nil
+}
+
+func normalize(text string) string {
+	text = strings.ToLower(text)
+	text = strings.NewReplacer("’", "'", "‘", "'").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// negated reports whether one of the last few words before a match negates it.
+func negated(before string) bool {
+	words := wordPattern.FindAllString(before, -1)
+	if len(words) > negationWindow {
+		words = words[len(words)-negationWindow:]
+	}
+	for _, word := range words {
+		if negators[word] {
+			return true
+		}
+	}
+	return false
+}
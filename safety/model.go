@@ -0,0 +1,59 @@
+package safety
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CompleteFunc asks a language model to classify text and returns its raw reply.
+type CompleteFunc func(text string) (string, error)
+
+// ModelClassifier delegates classification to a language model. The model is asked
+// to answer with JSON such as {"level": "concern", "categories": ["hopelessness"]}.
+// It is meant to be combined with the LexiconClassifier, never used alone, since it
+// fails whenever the model is unavailable.
+type ModelClassifier struct {
+	complete CompleteFunc
+}
+
+func NewModelClassifier(complete CompleteFunc) *ModelClassifier {
+	return &ModelClassifier{complete: complete}
+}
+
+func (mc *ModelClassifier) Classify(text string) (Assessment, error) {
+	reply, err := mc.complete(text)
+	if err != nil {
+		return Assessment{}, fmt.Errorf("safety model failed: %w", err)
+	}
+
+	// Models like to wrap JSON in prose or code fences; take the outermost object.
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return Assessment{}, fmt.Errorf("safety model returned no JSON: %q", reply)
+	}
+	var parsed struct {
+		Level      string   `json:"level"`
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &parsed); err != nil {
+		return Assessment{}, fmt.Errorf("safety model returned invalid JSON: %w", err)
+	}
+	level, err := ParseLevel(parsed.Level)
+	if err != nil {
+		return Assessment{}, err
+	}
+
+	assessment := Assessment{Level: level, Source: SourceModel}
+	if assessment.Flagged() {
+		known := map[string]bool{CategorySuicidalIdeation: true, CategorySelfHarm: true, CategoryHopelessness: true}
+		categories := make(map[string]bool)
+		for _, category := range parsed.Categories {
+			if category = strings.ToLower(strings.TrimSpace(category)); known[category] {
+				categories[category] = true
+			}
+		}
+		assessment.Categories = sortedKeys(categories)
+	}
+	return assessment, nil
+}
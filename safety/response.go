@@ -0,0 +1,26 @@
+package safety
+
+import "strings"
+
+// SupportiveResponse is shown in place of a reflection for a flagged entry. It is a
+// fixed template rather than model output, so nothing the model might say (or fail
+// to say) reaches someone at risk.
+func SupportiveResponse(assessment Assessment, resources []string) string {
+	var b strings.Builder
+	if assessment.Level >= LevelHigh {
+		b.WriteString("It sounds like you are carrying something really heavy right now, and it took courage to write it down. You don't have to face this alone. ")
+		b.WriteString("If you are thinking about ending your life or hurting yourself, please reach out to someone now:\n")
+	} else {
+		b.WriteString("Thank you for being honest about how hard things feel. Feelings like these can be overwhelming, and you deserve support with them. ")
+		b.WriteString("If it would help to talk to someone, these services are free and confidential:\n")
+	}
+	for _, resource := range resources {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			b.WriteString("\n- ")
+			b.WriteString(resource)
+		}
+	}
+	b.WriteString("\n\nIf you are in immediate danger, call your local emergency number. ")
+	b.WriteString("Talking with someone you trust, like a friend, family member or doctor, can help too.")
+	return b.String()
+}
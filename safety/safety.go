@@ -0,0 +1,119 @@
+package safety
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Level is how strongly a text signals risk of self-harm or suicide.
+type Level int
+
+const (
+	LevelNone Level = iota
+	// LevelConcern covers distress such as hopelessness without explicit intent.
+	LevelConcern
+	// LevelHigh covers explicit suicidal ideation or self-harm.
+	LevelHigh
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelConcern:
+		return "concern"
+	case LevelHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// ParseLevel is the inverse of Level.String.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none":
+		return LevelNone, nil
+	case "concern":
+		return LevelConcern, nil
+	case "high":
+		return LevelHigh, nil
+	}
+	return LevelNone, fmt.Errorf("unknown safety level %q", s)
+}
+
+// Categories of risk signal.
+const (
+	CategorySuicidalIdeation = "suicidal_ideation"
+	CategorySelfHarm         = "self_harm"
+	CategoryHopelessness     = "hopelessness"
+)
+
+// Sources of an assessment.
+const (
+	SourceLexicon = "lexicon"
+	SourceModel   = "model"
+)
+
+// Assessment is the result of classifying a text. It deliberately holds no excerpt of
+// the text, so it can be stored and logged.
+type Assessment struct {
+	Level      Level
+	Categories []string
+	Source     string
+}
+
+// Flagged reports whether the text should get a supportive response instead of an
+// ordinary reflection.
+func (a Assessment) Flagged() bool {
+	return a.Level > LevelNone
+}
+
+// Classifier screens text for risk of self-harm or suicide.
+type Classifier interface {
+	Classify(text string) (Assessment, error)
+}
+
+type combined []Classifier
+
+// Combine runs every classifier and keeps the most severe assessment, with the
+// categories of all of them. A classifier that fails does not hide what the others
+// found: the error is returned together with the best assessment available, so
+// callers can log it and carry on.
+func Combine(classifiers ...Classifier) Classifier {
+	return combined(classifiers)
+}
+
+func (c combined) Classify(text string) (Assessment, error) {
+	var result Assessment
+	var firstErr error
+	categories := make(map[string]bool)
+	for _, classifier := range c {
+		assessment, err := classifier.Classify(text)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if assessment.Level > result.Level {
+			result.Level = assessment.Level
+			result.Source = assessment.Source
+		}
+		for _, category := range assessment.Categories {
+			categories[category] = true
+		}
+	}
+	if result.Flagged() {
+		result.Categories = sortedKeys(categories)
+	}
+	return result, firstErr
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+package safety
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLexiconClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		level      Level
+		categories []string
+	}{
+		{"killed myself", "No one would miss me if I killed myself.", LevelHigh, []string{CategoryHopelessness, CategorySuicidalIdeation}},
+		{"kills myself", "Part of me thinks about how it ends if it kills myself first.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"killing myself", "I keep thinking about killing myself.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"never wake up", "I never want to wake up again.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"no longer alive", "I no longer want to be alive.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"don't wanna live", "Honestly I don't wanna live like this.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"curly apostrophe", "I don’t want to exist.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"took my life", "I nearly took my life last spring.", LevelHigh, []string{CategorySuicidalIdeation}},
+		{"self-harm", "The urge to self-harm came back tonight.", LevelHigh, []string{CategorySelfHarm}},
+		{"hopelessness", "Everything feels hopeless and I can't go on.", LevelConcern, []string{CategoryHopelessness}},
+
+		// A negated term is softened by one level, not dropped.
+		{"not suicidal", "I'm not suicidal, just exhausted.", LevelConcern, []string{CategorySuicidalIdeation}},
+		{"never hopeless", "I have never felt hopeless about this.", LevelNone, nil},
+		{"negator outside window", "I did not sleep well and then felt suicidal.", LevelHigh, []string{CategorySuicidalIdeation}},
+		// Phrases that carry their own negation are not softened again.
+		{"not want to live", "I do not want to live anymore.", LevelHigh, []string{CategorySuicidalIdeation}},
+
+		// Idioms stay unflagged.
+		{"movie killing me", "That movie was killing me, I laughed the whole time.", LevelNone, nil},
+		{"dying to", "I'm dying to see the new exhibition.", LevelNone, nil},
+		{"kill for coffee", "I would kill for a coffee right now.", LevelNone, nil},
+		{"deadline", "This deadline is going to be the death of me.", LevelNone, nil},
+		{"ordinary day", "Went for a walk by the river and felt calm afterwards.", LevelNone, nil},
+	}
+	lexicon := NewLexiconClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lexicon.Classify(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Level != tt.level || !reflect.DeepEqual(got.Categories, tt.categories) {
+				t.Errorf("got %s %v, want %s %v", got.Level, got.Categories, tt.level, tt.categories)
+			}
+			if got.Source != SourceLexicon {
+				t.Errorf("source = %q, want %q", got.Source, SourceLexicon)
+			}
+		})
+	}
+}
+
+func TestModelClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		err        error
+		level      Level
+		categories []string
+		wantErr    bool
+	}{
+		{"plain JSON", `{"level": "high", "categories": ["suicidal_ideation"]}`, nil, LevelHigh, []string{CategorySuicidalIdeation}, false},
+		{"wrapped in prose", "Sure.\n```json\n{\"level\": \"concern\", \"categories\": [\"Hopelessness \"]}\n```", nil, LevelConcern, []string{CategoryHopelessness}, false},
+		{"unknown categories dropped", `{"level": "high", "categories": ["self_harm", "sadness"]}`, nil, LevelHigh, []string{CategorySelfHarm}, false},
+		{"none drops categories", `{"level": "none", "categories": ["hopelessness"]}`, nil, LevelNone, nil, false},
+		{"no JSON", "I cannot help with that.", nil, LevelNone, nil, true},
+		{"invalid JSON", `{"level": "high",`, nil, LevelNone, nil, true},
+		{"unknown level", `{"level": "severe"}`, nil, LevelNone, nil, true},
+		{"model error", "", errors.New("timeout"), LevelNone, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := NewModelClassifier(func(string) (string, error) { return tt.reply, tt.err })
+			got, err := model.Classify("entry text")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error = %v", err, tt.wantErr)
+			}
+			if got.Level != tt.level || !reflect.DeepEqual(got.Categories, tt.categories) {
+				t.Errorf("got %s %v, want %s %v", got.Level, got.Categories, tt.level, tt.categories)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	reply := func(reply string, err error) Classifier {
+		return NewModelClassifier(func(string) (string, error) { return reply, err })
+	}
+	tests := []struct {
+		name       string
+		text       string
+		model      Classifier
+		level      Level
+		source     string
+		categories []string
+		wantErr    bool
+	}{
+		{
+			name:  "model misses what the lexicon finds",
+			text:  "I keep thinking about killing myself.",
+			model: reply(`{"level": "none"}`, nil),
+			level: LevelHigh, source: SourceLexicon, categories: []string{CategorySuicidalIdeation},
+		},
+		{
+			name:  "model finds what the lexicon misses",
+			text:  "I've been giving my things away and writing letters to everyone.",
+			model: reply(`{"level": "high", "categories": ["suicidal_ideation"]}`, nil),
+			level: LevelHigh, source: SourceModel, categories: []string{CategorySuicidalIdeation},
+		},
+		{
+			name:  "categories of both are kept",
+			text:  "Everything feels hopeless.",
+			model: reply(`{"level": "high", "categories": ["self_harm"]}`, nil),
+			level: LevelHigh, source: SourceModel, categories: []string{CategoryHopelessness, CategorySelfHarm},
+		},
+		{
+			name:  "malformed model reply falls back to the lexicon",
+			text:  "I no longer want to be alive.",
+			model: reply("not json", nil),
+			level: LevelHigh, source: SourceLexicon, categories: []string{CategorySuicidalIdeation}, wantErr: true,
+		},
+		{
+			name:  "failing model on a benign entry",
+			text:  "A quiet day at home.",
+			model: reply("", errors.New("unavailable")),
+			level: LevelNone, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Combine(NewLexiconClassifier(), tt.model).Classify(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error = %v", err, tt.wantErr)
+			}
+			if got.Level != tt.level || got.Source != tt.source || !reflect.DeepEqual(got.Categories, tt.categories) {
+				t.Errorf("got %s %q %v, want %s %q %v", got.Level, got.Source, got.Categories, tt.level, tt.source, tt.categories)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobFunc is the work performed by a scheduled job.
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	timeout  time.Duration
+	run      JobFunc
+	next     time.Time
+	running  bool
+}
+
+// Scheduler runs registered jobs on cron schedules. Every activation is guarded by a
+// lock document in Mongo so that only one replica executes a given run.
+type Scheduler struct {
+	runs  *mongo.Collection
+	locks *mongo.Collection
+	owner string
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	wake chan struct{}
+}
+
+func New(client *mongo.Client) *Scheduler {
+	db := client.Database(config.AppConfig.MongoDatabase)
+	return &Scheduler{
+		runs:  db.Collection("job_runs"),
+		locks: db.Collection("job_locks"),
+		owner: replicaID(),
+		jobs:  make(map[string]*job),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Register adds a job. The timeout bounds a single run and doubles as the lock lease.
+func (s *Scheduler) Register(name, spec string, timeout time.Duration, run JobFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already registered", name)
+	}
+	s.jobs[name] = &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		timeout:  timeout,
+		run:      run,
+		next:     schedule.Next(time.Now()),
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start runs the scheduling loop until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			wait := s.dispatchDue(ctx)
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// dispatchDue starts every job whose activation time has passed and returns how long
+// to sleep until the next one.
+func (s *Scheduler) dispatchDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Minute
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			// The schedule has no more activations.
+			continue
+		}
+		if !j.next.After(now) {
+			scheduledFor := j.next
+			j.next = j.schedule.Next(now)
+			if !j.running {
+				j.running = true
+				go s.execute(ctx, j, scheduledFor, "schedule")
+			}
+		}
+		if d := j.next.Sub(now); !j.next.IsZero() && d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// RunNow triggers a job immediately, outside its schedule.
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	if j.running {
+		s.mu.Unlock()
+		return fmt.Errorf("job already running")
+	}
+	j.running = true
+	s.mu.Unlock()
+
+	go s.execute(context.Background(), j, time.Now(), "manual")
+	return nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job, scheduledFor time.Time, trigger string) {
+	defer func() {
+		s.mu.Lock()
+		j.running = false
+		s.mu.Unlock()
+	}()
+
+	acquired, err := s.acquire(j, scheduledFor)
+	if err != nil {
+		log.Printf("scheduler: failed to acquire lock for %s: %v", j.name, err)
+		return
+	}
+	if !acquired {
+		return // another replica owns this run
+	}
+	defer s.release(j)
+
+	run := &models.JobRun{
+		Job:          j.name,
+		Owner:        s.owner,
+		Trigger:      trigger,
+		ScheduledFor: scheduledFor,
+		StartedAt:    time.Now(),
+		Status:       "running",
+	}
+	if result, err := s.runs.InsertOne(context.Background(), run); err == nil {
+		run.ID = result.InsertedID.(primitive.ObjectID)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, j.timeout)
+	runErr := safeRun(runCtx, j.run)
+	cancel()
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMs = finished.Sub(run.StartedAt).Milliseconds()
+	run.Status = "succeeded"
+	if runErr != nil {
+		run.Status = "failed"
+		run.Error = runErr.Error()
+		log.Printf("scheduler: job %s failed: %v", j.name, runErr)
+	}
+
+	if !run.ID.IsZero() {
+		s.runs.ReplaceOne(context.Background(), bson.M{"_id": run.ID}, run)
+	}
+}
+
+func safeRun(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// acquire takes the job's lease for one activation. The lease is granted only if it
+// has expired and no replica has already claimed an activation at or after scheduledFor.
+func (s *Scheduler) acquire(j *job, scheduledFor time.Time) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":          j.name,
+		"locked_until": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"last_scheduled_for": bson.M{"$lt": scheduledFor}},
+			{"last_scheduled_for": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":              s.owner,
+		"locked_until":       now.Add(j.timeout),
+		"last_scheduled_for": scheduledFor,
+		"acquired_at":        now,
+	}}
+
+	_, err := s.locks.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Scheduler) release(j *job) {
+	filter := bson.M{"_id": j.name, "owner": s.owner}
+	update := bson.M{"$set": bson.M{"locked_until": time.Now()}}
+	if _, err := s.locks.UpdateOne(context.Background(), filter, update); err != nil {
+		log.Printf("scheduler: failed to release lock for %s: %v", j.name, err)
+	}
+}
+
+// Jobs lists registered jobs with their next activation and most recent run.
+func (s *Scheduler) Jobs() ([]models.JobStatus, error) {
+	s.mu.Lock()
+	statuses := make([]models.JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, models.JobStatus{
+			Name:    j.name,
+			Spec:    j.spec,
+			NextRun: j.next,
+			Running: j.running,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+
+	for i := range statuses {
+		runs, err := s.History(statuses[i].Name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			statuses[i].LastRun = &runs[0]
+		}
+	}
+	return statuses, nil
+}
+
+// History returns the most recent runs of a job across all replicas.
+func (s *Scheduler) History(name string, limit int64) ([]models.JobRun, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(limit)
+	cursor, err := s.runs.Find(context.Background(), bson.M{"job": name}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job runs: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var runs []models.JobRun
+	if err = cursor.All(context.Background(), &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// HasJob reports whether a job with the given name is registered.
+func (s *Scheduler) HasJob(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.jobs[name]
+	return ok
+}
+
+// PruneHistory deletes job runs that started before the cutoff.
+func (s *Scheduler) PruneHistory(olderThan time.Duration) (int64, error) {
+	result, err := s.runs.DeleteMany(context.Background(), bson.M{"started_at": bson.M{"$lt": time.Now().Add(-olderThan)}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune job runs: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// EnsureIndexes creates the indexes used by run history queries.
+func (s *Scheduler) EnsureIndexes(ctx context.Context) error {
+	_, err := s.runs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "job", Value: 1}, {Key: "started_at", Value: -1}},
+	})
+	return err
+}
+
+func replicaID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -3,10 +3,15 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"soulprint-backend/config"
+	"soulprint-backend/encryption"
 	"soulprint-backend/models"
+	"soulprint-backend/redact"
+	"soulprint-backend/safety"
 	"soulprint-backend/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,19 +25,85 @@ type AIService struct {
 	collection       *mongo.Collection
 	journalService   *JournalService
 	openaiClient     *utils.OpenAIClient
+	webhooks         *WebhookService
+	encryptor        *encryption.Encryptor
+	usage            *UsageService
+	lexicon          *safety.LexiconClassifier
+	safetyEvents     *mongo.Collection
+	feedback         *mongo.Collection
 }
 
-func NewAIService(client *mongo.Client, journalService *JournalService) *AIService {
-	collection := client.Database(config.AppConfig.MongoDatabase).Collection("reflections")
+func NewAIService(client *mongo.Client, journalService *JournalService, webhooks *WebhookService, encryptor *encryption.Encryptor, usage *UsageService) *AIService {
+	db := client.Database(config.AppConfig.MongoDatabase)
 	return &AIService{
 		client:         client,
-		collection:     collection,
+		collection:     db.Collection("reflections"),
 		journalService: journalService,
 		openaiClient:   utils.NewOpenAIClient(),
+		webhooks:       webhooks,
+		encryptor:      encryptor,
+		usage:          usage,
+		lexicon:        safety.NewLexiconClassifier(),
+		safetyEvents:   db.Collection("safety_events"),
+		feedback:       db.Collection("reflection_feedback"),
 	}
 }
 
+// clientFor returns the AI client for calls made on behalf of a user, with their
+// token usage metered.
+func (ais *AIService) clientFor(userID string) *utils.OpenAIClient {
+	return ais.openaiClient.WithUsage(ais.usage.Recorder(userID))
+}
+
+// EnsureIndexes creates the indexes used by reflection queries.
+func (ais *AIService) EnsureIndexes(ctx context.Context) error {
+	_, err := ais.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "entry_id", Value: 1}}},
+		{Keys: bson.D{{Key: "entry_ids", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "root_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ais.safetyEvents.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "entry_id", Value: 1}, {Key: "context", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ais.feedback.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "reflection_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "entry_id", Value: 1}}},
+	})
+	return err
+}
+
 func (ais *AIService) GenerateReflection(userID string, req models.ReflectionRequest) (*models.Reflection, error) {
+	return ais.generateReflection(userID, req, generateOptions{})
+}
+
+// generateOptions are the internal knobs of generateReflection.
+type generateOptions struct {
+	// beforeCall runs right before the model is first called, after the cache, budget
+	// and lexicon safety checks, so callers can rate limit actual provider calls.
+	beforeCall func()
+	// parent is the reflection being regenerated.
+	parent *models.Reflection
+}
+
+func (ais *AIService) generateReflection(userID string, req models.ReflectionRequest, opts generateOptions) (*models.Reflection, error) {
+	if _, ok := utils.ReflectionTones[req.Tone]; req.Tone != "" && !ok {
+		return nil, fmt.Errorf("invalid request: unknown tone %q", req.Tone)
+	}
+	if _, ok := utils.ReflectionLengths[req.Length]; req.Length != "" && !ok {
+		return nil, fmt.Errorf("invalid request: unknown length %q", req.Length)
+	}
+
 	// Get the journal entry
 	entry, err := ais.journalService.GetEntryByID(userID, req.EntryID)
 	if err != nil {
@@ -45,37 +116,213 @@ func (ais *AIService) GenerateReflection(userID string, req models.ReflectionReq
 		reflectionType = "insight"
 	}
 
+	// End-to-end encrypted entries can only be reflected on with a decrypted copy from
+	// the client. That copy, and the reflection derived from it, are never stored.
+	content := entry.Content
+	transient := entry.Envelope != nil
+	if transient {
+		if req.Content == "" {
+			return nil, fmt.Errorf("invalid request: entry is end-to-end encrypted, send a decrypted copy in content")
+		}
+		content = req.Content
+	}
+	plaintext := content
+
+	redactor, err := ais.redactorFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	content = redactor.Redact(content)
+
+	// Entries signalling risk of self-harm get a fixed supportive response with crisis
+	// resources, never a model-written insight. The lexicon screens first since it is
+	// free; the metered model classifier runs after the cache and budget checks.
+	client := ais.clientFor(userID)
+	screened := redactor.Redact(entry.Title) + "\n" + content
+	if assessment, _ := ais.lexicon.Classify(screened); assessment.Flagged() {
+		return ais.supportiveReflection(userID, entry.ID, assessment, transient, "")
+	}
+
+	lang, err := ais.reflectionLanguage(userID, entry, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reflections on stored entries are cached by their inputs. End-to-end encrypted
+	// entries are never cached since their reflections are not stored.
+	cacheKey := ""
+	if !transient {
+		cacheKey = reflectionCacheKey(entry, reflectionType, req.Tone, req.Length, lang, client.Model())
+		if !req.Force {
+			cached, err := ais.cachedReflection(userID, entry.ID, cacheKey)
+			if err != nil {
+				return nil, err
+			}
+			if cached != nil {
+				return cached, nil
+			}
+		}
+	}
+
+	if err := ais.usage.CheckBudget(userID); err != nil {
+		return nil, err
+	}
+	if opts.beforeCall != nil {
+		opts.beforeCall()
+	}
+
+	// The supportive response is stored under the cache key, so a cache hit reuses the
+	// model's verdict instead of classifying the same text again.
+	if config.AppConfig.SafetyModelClassifier {
+		if assessment := ais.assessSafety(client, screened); assessment.Flagged() {
+			return ais.supportiveReflection(userID, entry.ID, assessment, transient, cacheKey)
+		}
+	}
+
 	// Generate AI reflection
-	reflectionContent, err := ais.openaiClient.GenerateReflection(entry.Content, reflectionType)
+	reflectionContent, err := client.GenerateReflection(content, reflectionType, utils.ReflectionStyle{Tone: req.Tone, Length: req.Length, Language: lang})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate AI reflection: %w", err)
 	}
+	reflectionContent = redactor.Restore(reflectionContent)
 
-	// Extract keywords (optional, can fail gracefully)
-	keywords, _ := ais.openaiClient.ExtractKeywords(entry.Content)
+	keywords := ais.reflectionKeywords(client, redactor, entry, plaintext, content)
 
 	// Create reflection record
 	reflection := &models.Reflection{
-		EntryID:   entry.ID,
+		EntryID:       entry.ID,
+		UserID:        userID,
+		Content:       reflectionContent,
+		Type:          reflectionType,
+		Keywords:      keywords,
+		Sentiment:     ais.extractSentiment(reflectionContent, lang), // Simple sentiment analysis
+		Language:      lang,
+		Model:         client.Model(),
+		PromptVersion: utils.PromptVersion,
+		Tone:          req.Tone,
+		Length:        req.Length,
+		CacheKey:      cacheKey,
+		CreatedAt:     time.Now(),
+	}
+	if opts.parent != nil {
+		reflection.ParentID = &opts.parent.ID
+		reflection.RootID = opts.parent.RootID
+		if reflection.RootID == nil {
+			reflection.RootID = &opts.parent.ID
+		}
+	}
+	if transient {
+		return reflection, nil
+	}
+
+	stored, err := sealReflection(ais.encryptor, reflection)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ais.collection.InsertOne(context.Background(), stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save reflection: %w", err)
+	}
+
+	reflection.ID = result.InsertedID.(primitive.ObjectID)
+	ais.webhooks.Publish(userID, EventReflectionCreated, reflection)
+	return reflection, nil
+}
+
+// assessSafety classifies text sent for reflection, with the model as well as the
+// lexicon when SAFETY_MODEL_CLASSIFIER is set. A failing model classifier is logged
+// and the lexicon result used, so screening never blocks on the model.
+func (ais *AIService) assessSafety(client *utils.OpenAIClient, text string) safety.Assessment {
+	var classifier safety.Classifier = ais.lexicon
+	if config.AppConfig.SafetyModelClassifier {
+		classifier = safety.Combine(ais.lexicon, safety.NewModelClassifier(client.ClassifyRisk))
+	}
+	assessment, err := classifier.Classify(text)
+	if err != nil {
+		log.Printf("Safety classifier error: %v", err)
+	}
+	return assessment
+}
+
+// supportiveReflection stands in for the reflection on a flagged entry. The flag is
+// always recorded, even for end-to-end encrypted entries whose reflection is not
+// stored; the record holds the level and categories but no text. A non-empty cacheKey
+// lets later requests with the same inputs be served the stored response.
+func (ais *AIService) supportiveReflection(userID string, entryID primitive.ObjectID, assessment safety.Assessment, transient bool, cacheKey string) (*models.Reflection, error) {
+	flag := safetyFlag(assessment)
+	if err := ais.recordSafetyEvent(userID, entryID, flag, "reflection"); err != nil {
+		return nil, err
+	}
+
+	reflection := &models.Reflection{
+		EntryID:   entryID,
 		UserID:    userID,
-		Content:   reflectionContent,
-		Type:      reflectionType,
-		Keywords:  keywords,
-		Sentiment: ais.extractSentiment(reflectionContent), // Simple sentiment analysis
+		Content:   safety.SupportiveResponse(assessment, config.AppConfig.CrisisResources),
+		Type:      "support",
+		Safety:    flag,
+		CacheKey:  cacheKey,
 		CreatedAt: time.Now(),
 	}
+	if transient {
+		return reflection, nil
+	}
 
-	result, err := ais.collection.InsertOne(context.Background(), reflection)
+	stored, err := sealReflection(ais.encryptor, reflection)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ais.collection.InsertOne(context.Background(), stored)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save reflection: %w", err)
 	}
-
 	reflection.ID = result.InsertedID.(primitive.ObjectID)
+	// Not published: a safety flag is not something to hand to third-party webhooks.
 	return reflection, nil
 }
 
+// recordSafetyEvent records that an entry was flagged in the given context. There is
+// one event per entry and context: screening the entry again, as every digest
+// regeneration does, updates the flag but keeps the time it was first raised.
+func (ais *AIService) recordSafetyEvent(userID string, entryID primitive.ObjectID, flag *models.SafetyFlag, kind string) error {
+	filter := bson.M{"user_id": userID, "entry_id": entryID, "context": kind}
+	update := bson.M{
+		"$set":         bson.M{"level": flag.Level, "categories": flag.Categories, "source": flag.Source},
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}
+	if _, err := ais.safetyEvents.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to record safety flag: %w", err)
+	}
+	return nil
+}
+
+func safetyFlag(assessment safety.Assessment) *models.SafetyFlag {
+	return &models.SafetyFlag{
+		Level:      assessment.Level.String(),
+		Categories: assessment.Categories,
+		Source:     assessment.Source,
+	}
+}
+
+// redactorFor returns the PII redactor for text sent to the AI provider on behalf of
+// a user, or nil when the user turned redaction off or the model runs locally (or is
+// the fake provider) and nothing leaves the machine.
+func (ais *AIService) redactorFor(userID string) (*redact.Redactor, error) {
+	if config.AppConfig.AIProvider != "openai" {
+		return nil, nil
+	}
+	settings, err := ais.journalService.settings.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !*settings.RedactPII {
+		return nil, nil
+	}
+	return redact.New(settings.SensitiveNames), nil
+}
+
 func (ais *AIService) GetReflections(userID string) ([]models.Reflection, error) {
-	filter := bson.M{"user_id": userID}
+	filter := bson.M{"user_id": userID, "deleted_at": nil}
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 
 	cursor, err := ais.collection.Find(context.Background(), filter, opts)
@@ -88,6 +335,9 @@ func (ais *AIService) GetReflections(userID string) ([]models.Reflection, error)
 	if err = cursor.All(context.Background(), &reflections); err != nil {
 		return nil, fmt.Errorf("failed to decode reflections: %w", err)
 	}
+	if err := openReflections(ais.encryptor, reflections); err != nil {
+		return nil, err
+	}
 
 	return reflections, nil
 }
@@ -99,8 +349,9 @@ func (ais *AIService) GetReflectionsByEntry(userID, entryID string) ([]models.Re
 	}
 
 	filter := bson.M{
-		"entry_id": objectID,
-		"user_id":  userID,
+		"entry_id":   objectID,
+		"user_id":    userID,
+		"deleted_at": nil,
 	}
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 
@@ -114,6 +365,36 @@ func (ais *AIService) GetReflectionsByEntry(userID, entryID string) ([]models.Re
 	if err = cursor.All(context.Background(), &reflections); err != nil {
 		return nil, fmt.Errorf("failed to decode reflections: %w", err)
 	}
+	if err := openReflections(ais.encryptor, reflections); err != nil {
+		return nil, err
+	}
+
+	return reflections, nil
+}
+
+// GetReflectionsInRange returns a user's per-entry reflections created in [from, to).
+func (ais *AIService) GetReflectionsInRange(userID string, from, to time.Time) ([]models.Reflection, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"type":       bson.M{"$ne": "digest"},
+		"created_at": bson.M{"$gte": from, "$lt": to},
+		"deleted_at": nil,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := ais.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reflections: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var reflections []models.Reflection
+	if err = cursor.All(context.Background(), &reflections); err != nil {
+		return nil, fmt.Errorf("failed to decode reflections: %w", err)
+	}
+	if err := openReflections(ais.encryptor, reflections); err != nil {
+		return nil, err
+	}
 
 	return reflections, nil
 }
@@ -137,28 +418,19 @@ func (ais *AIService) GetInsights(userID string) (map[string]interface{}, error)
 }
 
 // Helper methods
-func (ais *AIService) extractSentiment(content string) string {
-	// Simple sentiment analysis based on keywords
+func (ais *AIService) extractSentiment(content, lang string) string {
+	return sentiment(content, lang)
+}
+
+func sentiment(content, lang string) string {
+	// Simple sentiment analysis based on keywords in the text's language
 	// In production, you might want to use a proper sentiment analysis library or API
-	positive := []string{"happy", "joy", "grateful", "excited", "love", "wonderful", "amazing", "great"}
-	negative := []string{"sad", "angry", "frustrated", "worried", "anxious", "terrible", "awful", "horrible"}
-	
-	positiveCount := 0
-	negativeCount := 0
-	
-	contentLower := content
-	for _, word := range positive {
-		if contains(contentLower, word) {
-			positiveCount++
-		}
-	}
-	
-	for _, word := range negative {
-		if contains(contentLower, word) {
-			negativeCount++
-		}
-	}
-	
+	positive, negative := sentimentLexicon(content, lang)
+
+	contentLower := strings.ToLower(content)
+	positiveCount := countMatches(contentLower, positive)
+	negativeCount := countMatches(contentLower, negative)
+
 	if positiveCount > negativeCount {
 		return "positive"
 	} else if negativeCount > positiveCount {
@@ -219,17 +491,3 @@ func (ais *AIService) countReflectionTypes(reflections []models.Reflection) map[
 	
 	return types
 }
-
-// Helper function to check if string contains substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || containsMiddle(s, substr)))
-}
-
-func containsMiddle(s, substr string) bool {
-	for i := 1; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-} 
\ No newline at end of file
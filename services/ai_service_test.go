@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newFakeAIService returns an AIService on the fake provider. The Mongo client never
+// connects, so only code paths that stay off the database can be tested with it.
+func newFakeAIService(t *testing.T, cfg config.Config) *AIService {
+	t.Helper()
+	previous := config.AppConfig
+	cfg.AIProvider = "fake"
+	cfg.MongoDatabase = "soulprint_test"
+	config.AppConfig = &cfg
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return NewAIService(client, nil, nil, nil, nil)
+}
+
+func TestAssessSafetyWithFakeModel(t *testing.T) {
+	ais := newFakeAIService(t, config.Config{SafetyModelClassifier: true})
+
+	tests := []struct {
+		name    string
+		text    string
+		flagged bool
+	}{
+		{"at risk", "I keep thinking about killing myself.", true},
+		{"benign", "Went for a long walk by the river and felt calm afterwards.", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := ais.assessSafety(ais.openaiClient, tt.text)
+			if assessment.Flagged() != tt.flagged {
+				t.Errorf("flagged = %v, want %v (%+v)", assessment.Flagged(), tt.flagged, assessment)
+			}
+		})
+	}
+}
+
+func TestReflectionKeywordsWithFakeModel(t *testing.T) {
+	entry := &models.JournalEntry{
+		ID:       primitive.NewObjectID(),
+		UserID:   "user123",
+		Title:    "Garden",
+		Content:  "The garden was full of tomatoes. I spent the evening in the garden watering the tomatoes.",
+		Keywords: []models.Keyword{{Term: "offline", Score: 1}},
+	}
+
+	t.Run("model", func(t *testing.T) {
+		ais := newFakeAIService(t, config.Config{KeywordExtractor: "model"})
+		terms := ais.reflectionKeywords(ais.openaiClient, nil, entry, entry.Content, entry.Content)
+		if len(terms) < 2 || terms[0] != "garden" || terms[1] != "tomatoes" {
+			t.Errorf("terms = %v, want garden and tomatoes first", terms)
+		}
+	})
+
+	t.Run("model failure falls back to stored keywords", func(t *testing.T) {
+		ais := newFakeAIService(t, config.Config{KeywordExtractor: "model", FakeLLMFailOperations: []string{"keywords"}})
+		terms := ais.reflectionKeywords(ais.openaiClient, nil, entry, entry.Content, entry.Content)
+		if len(terms) != 1 || terms[0] != "offline" {
+			t.Errorf("terms = %v, want [offline]", terms)
+		}
+	})
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+	"soulprint-backend/redact"
+	"soulprint-backend/safety"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Digest periods supported by the digest generator.
+var DigestPeriods = []string{"weekly", "monthly", "yearly"}
+
+type DigestService struct {
+	client         *mongo.Client
+	collection     *mongo.Collection
+	journalService *JournalService
+	aiService      *AIService
+}
+
+func NewDigestService(client *mongo.Client, journalService *JournalService, aiService *AIService) *DigestService {
+	collection := client.Database(config.AppConfig.MongoDatabase).Collection("reflections")
+	return &DigestService{
+		client:         client,
+		collection:     collection,
+		journalService: journalService,
+		aiService:      aiService,
+	}
+}
+
+// GenerateDigest builds (or rebuilds) the digest reflection for the window described by req.
+func (ds *DigestService) GenerateDigest(userID string, req models.DigestRequest) (*models.Reflection, error) {
+	ref := time.Now().UTC()
+	if req.Date != "" {
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date, expected YYYY-MM-DD")
+		}
+		ref = date
+	} else {
+		start, _, err := DigestWindow(req.Period, ref)
+		if err != nil {
+			return nil, err
+		}
+		ref = start.Add(-time.Nanosecond)
+	}
+
+	start, end, err := DigestWindow(req.Period, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.generateForWindow(userID, req.Period, start, end)
+}
+
+func (ds *DigestService) GetDigests(userID, period string) ([]models.Reflection, error) {
+	filter := bson.M{"user_id": userID, "type": "digest"}
+	if period != "" {
+		filter["period"] = period
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "period_start", Value: -1}})
+
+	cursor, err := ds.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find digests: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var digests []models.Reflection
+	if err = cursor.All(context.Background(), &digests); err != nil {
+		return nil, fmt.Errorf("failed to decode digests: %w", err)
+	}
+	if err := openReflections(ds.aiService.encryptor, digests); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
+
+// GenerateDueDigests creates any digest for the last completed week, month and year
+// that does not exist yet. Users without entries in a window are skipped. It stops
+// between digests once ctx is done, so a run does not outlive its job lease.
+func (ds *DigestService) GenerateDueDigests(ctx context.Context) (int, error) {
+	userIDs, err := ds.journalService.GetUserIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	created := 0
+	for _, period := range DigestPeriods {
+		current, _, _ := DigestWindow(period, now)
+		start, end, _ := DigestWindow(period, current.Add(-time.Nanosecond))
+
+		for _, userID := range userIDs {
+			if err := ctx.Err(); err != nil {
+				return created, err
+			}
+			if e2e, err := ds.journalService.settings.E2EEnabled(userID); err != nil || e2e {
+				continue
+			}
+
+			exists, err := ds.digestExists(ctx, userID, period, start)
+			if err != nil {
+				return created, err
+			}
+			if exists {
+				continue
+			}
+
+			digest, err := ds.generateForWindow(userID, period, start, end)
+			if err != nil {
+				if err.Error() == "no journal entries in digest window" {
+					continue
+				}
+				log.Printf("digest: failed to generate %s digest for %s: %v", period, userID, err)
+				continue
+			}
+			if digest != nil {
+				created++
+			}
+		}
+	}
+
+	return created, nil
+}
+
+func (ds *DigestService) generateForWindow(userID, period string, start, end time.Time) (*models.Reflection, error) {
+	e2e, err := ds.journalService.settings.E2EEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if e2e {
+		return nil, fmt.Errorf("invalid request: digests are unavailable for end-to-end encrypted journals")
+	}
+
+	entries, err := ds.journalService.GetEntriesInRange(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	entries = readableEntries(entries)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no journal entries in digest window")
+	}
+
+	lang, err := ds.aiService.digestLanguage(userID, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries at risk are screened out before anything is sent to the model. A digest
+	// covering them leads with the supportive response and its crisis resources, and
+	// one covering nothing else makes no model call at all.
+	assessment, unflagged, err := ds.screenEntries(userID, entries, "digest")
+	if err != nil {
+		return nil, err
+	}
+	var content string
+	if len(unflagged) > 0 {
+		redactor, err := ds.aiService.redactorFor(userID)
+		if err != nil {
+			return nil, err
+		}
+		content, err = ds.summarize(userID, unflagged, period, lang, redactor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate digest: %w", err)
+		}
+	}
+	var flag *models.SafetyFlag
+	if assessment.Flagged() {
+		flag = safetyFlag(assessment)
+		content = strings.TrimSpace(safety.SupportiveResponse(assessment, config.AppConfig.CrisisResources) + "\n\n" + content)
+	}
+
+	entryIDs := make([]primitive.ObjectID, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.ID
+	}
+
+	digest := &models.Reflection{
+		EntryIDs:    entryIDs,
+		UserID:      userID,
+		Content:     content,
+		Type:        "digest",
+		Period:      period,
+		PeriodStart: &start,
+		PeriodEnd:   &end,
+		Sentiment:   ds.aiService.extractSentiment(content, lang),
+		Safety:      flag,
+		Language:    lang,
+		CreatedAt:   time.Now(),
+	}
+
+	stored, err := sealReflection(ds.aiService.encryptor, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// One digest per user, period and window: regenerating replaces the previous one.
+	filter := bson.M{"user_id": userID, "type": "digest", "period": period, "period_start": start}
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	if err := ds.collection.FindOneAndReplace(context.Background(), filter, stored, opts).Decode(digest); err != nil {
+		return nil, fmt.Errorf("failed to save digest: %w", err)
+	}
+	digest.Content = content
+
+	if flag == nil {
+		ds.aiService.webhooks.Publish(userID, EventReflectionCreated, digest)
+	}
+	return digest, nil
+}
+
+// screenEntries runs the offline safety screen over the entries of a digest or report
+// (kind) and records a flag for each one at risk. It returns the most severe
+// assessment and the entries that were not flagged. Only the lexicon is used here so
+// a digest does not cost one model call per entry.
+func (ds *DigestService) screenEntries(userID string, entries []models.JournalEntry, kind string) (safety.Assessment, []models.JournalEntry, error) {
+	var worst safety.Assessment
+	var unflagged []models.JournalEntry
+	categories := make(map[string]bool)
+	for _, entry := range entries {
+		assessment, _ := ds.aiService.lexicon.Classify(entry.Title + "\n" + entry.Content)
+		if !assessment.Flagged() {
+			unflagged = append(unflagged, entry)
+			continue
+		}
+		if err := ds.aiService.recordSafetyEvent(userID, entry.ID, safetyFlag(assessment), kind); err != nil {
+			return worst, nil, err
+		}
+		if assessment.Level > worst.Level {
+			worst.Level = assessment.Level
+			worst.Source = assessment.Source
+		}
+		for _, category := range assessment.Categories {
+			categories[category] = true
+		}
+	}
+	for category := range categories {
+		worst.Categories = append(worst.Categories, category)
+	}
+	sort.Strings(worst.Categories)
+	return worst, unflagged, nil
+}
+
+// summarize produces the digest text in lang, folding entries into intermediate
+// summaries until everything fits in the model's context budget. With a redactor,
+// personal data is replaced before any text is sent and restored in the final digest.
+// Calls are metered against the user's token budget.
+func (ds *DigestService) summarize(userID string, entries []models.JournalEntry, period, lang string, redactor *redact.Redactor) (string, error) {
+	if err := ds.aiService.usage.CheckBudget(userID); err != nil {
+		return "", err
+	}
+	client := ds.aiService.clientFor(userID)
+
+	budget := config.AppConfig.AIContextTokens
+	sections := make([]string, len(entries))
+	for i, entry := range entries {
+		sections[i] = truncateToTokens(redactor.Redact(formatEntryForDigest(entry)), budget)
+	}
+
+	for estimateTokens(strings.Join(sections, "\n\n")) > budget && len(sections) > 1 {
+		chunks := chunkByTokens(sections, budget)
+		if len(chunks) == len(sections) {
+			// Nothing can be merged any more; trim sections so the final prompt still fits.
+			for i := range sections {
+				sections[i] = truncateToTokens(sections[i], budget/len(sections))
+			}
+			break
+		}
+		summaries := make([]string, 0, len(chunks))
+		for _, chunk := range chunks {
+			summary, err := client.SummarizeEntries(chunk, period, lang)
+			if err != nil {
+				return "", err
+			}
+			summaries = append(summaries, strings.TrimSpace(summary))
+		}
+		sections = summaries
+	}
+
+	content, err := client.GenerateDigest(sections, period, lang)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(redactor.Restore(content)), nil
+}
+
+// EnsureIndexes creates the indexes used by digest lookups.
+func (ds *DigestService) EnsureIndexes(ctx context.Context) error {
+	_, err := ds.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "period", Value: 1}, {Key: "period_start", Value: -1}},
+	})
+	return err
+}
+
+func (ds *DigestService) digestExists(ctx context.Context, userID, period string, start time.Time) (bool, error) {
+	filter := bson.M{"user_id": userID, "type": "digest", "period": period, "period_start": start}
+	count, err := ds.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to check digest: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DigestWindow returns the [start, end) window of the given period containing ref, in UTC.
+// Weeks start on Monday.
+func DigestWindow(period string, ref time.Time) (time.Time, time.Time, error) {
+	ref = ref.UTC()
+	day := time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case "weekly":
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case "monthly":
+		start := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), nil
+	case "yearly":
+		start := time.Date(ref.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid digest period %q", period)
+	}
+}
+
+func formatEntryForDigest(entry models.JournalEntry) string {
+	header := fmt.Sprintf("[%s] %s", entry.CreatedAt.Format("2006-01-02"), entry.Title)
+	if entry.Mood != "" {
+		header += fmt.Sprintf(" (mood: %s)", entry.Mood)
+	}
+	return header + "\n" + entry.Content
+}
+
+// estimateTokens uses the usual ~4 characters per token rule of thumb.
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+func truncateToTokens(text string, tokens int) string {
+	if limit := tokens * 4; len(text) > limit {
+		for limit > 0 && !utf8.RuneStart(text[limit]) {
+			limit--
+		}
+		return text[:limit]
+	}
+	return text
+}
+
+func chunkByTokens(sections []string, budget int) [][]string {
+	var chunks [][]string
+	var current []string
+	size := 0
+	for _, section := range sections {
+		tokens := estimateTokens(section)
+		if len(current) > 0 && size+tokens > budget {
+			chunks = append(chunks, current)
+			current = nil
+			size = 0
+		}
+		current = append(current, section)
+		size += tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
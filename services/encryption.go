@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+
+	"soulprint-backend/encryption"
+	"soulprint-backend/models"
+)
+
+// EncryptedFields lists, per collection, the fields stored encrypted at rest. Paths
+// through arrays apply to every element. cmd/rotatekeys re-encrypts exactly these.
+var EncryptedFields = map[string][]string{
+	"journal_entries":         {"title", "content", "keywords.term", "answers.value"},
+	"journal_entry_revisions": {"title", "content", "answers.value"},
+	"reflections":             {"content", "keywords"},
+	"reflection_feedback":     {"comment"},
+	"topics":                  {"label", "terms"},
+	"user_settings":           {"sensitive_names"},
+	"webhook_deliveries":      {"payload"},
+	"year_reviews":            {"narrative", "highlights.title", "highlights.excerpt"},
+}
+
+// sealFields encrypts the given fields in place.
+func sealFields(enc *encryption.Encryptor, userID string, fields ...*string) error {
+	for _, field := range fields {
+		sealed, err := enc.Encrypt(userID, *field)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %w", err)
+		}
+		*field = sealed
+	}
+	return nil
+}
+
+// openFields decrypts the given fields in place.
+func openFields(enc *encryption.Encryptor, userID string, fields ...*string) error {
+	for _, field := range fields {
+		plaintext, err := enc.Decrypt(userID, *field)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt: %w", err)
+		}
+		*field = plaintext
+	}
+	return nil
+}
+
+// entryFields returns the encrypted fields of an entry.
+func entryFields(entry *models.JournalEntry) []*string {
+	fields := []*string{&entry.Title, &entry.Content}
+	for i := range entry.Keywords {
+		fields = append(fields, &entry.Keywords[i].Term)
+	}
+	for i := range entry.Answers {
+		fields = append(fields, &entry.Answers[i].Value)
+	}
+	return fields
+}
+
+func revisionFields(revision *models.EntryRevision) []*string {
+	fields := []*string{&revision.Title, &revision.Content}
+	for i := range revision.Answers {
+		fields = append(fields, &revision.Answers[i].Value)
+	}
+	return fields
+}
+
+// sealEntry returns an encrypted copy of the entry; the original is left as is.
+func sealEntry(enc *encryption.Encryptor, entry *models.JournalEntry) (*models.JournalEntry, error) {
+	sealed := *entry
+	sealed.Keywords = append([]models.Keyword(nil), entry.Keywords...)
+	sealed.Answers = append([]models.FieldAnswer(nil), entry.Answers...)
+	if err := sealFields(enc, entry.UserID, entryFields(&sealed)...); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+func openEntry(enc *encryption.Encryptor, entry *models.JournalEntry) error {
+	return openFields(enc, entry.UserID, entryFields(entry)...)
+}
+
+func openEntries(enc *encryption.Encryptor, entries []models.JournalEntry) error {
+	for i := range entries {
+		if err := openEntry(enc, &entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func reflectionFields(reflection *models.Reflection) []*string {
+	fields := []*string{&reflection.Content}
+	for i := range reflection.Keywords {
+		fields = append(fields, &reflection.Keywords[i])
+	}
+	return fields
+}
+
+// sealReflection returns an encrypted copy of the reflection; the original is left as is.
+func sealReflection(enc *encryption.Encryptor, reflection *models.Reflection) (*models.Reflection, error) {
+	sealed := *reflection
+	sealed.Keywords = append([]string(nil), reflection.Keywords...)
+	if err := sealFields(enc, reflection.UserID, reflectionFields(&sealed)...); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+func openReflection(enc *encryption.Encryptor, reflection *models.Reflection) error {
+	return openFields(enc, reflection.UserID, reflectionFields(reflection)...)
+}
+
+func openReflections(enc *encryption.Encryptor, reflections []models.Reflection) error {
+	for i := range reflections {
+		if err := openReflection(enc, &reflections[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sealYearReview returns an encrypted copy of the review; the original is left as is.
+func sealYearReview(enc *encryption.Encryptor, review *models.YearReview) (*models.YearReview, error) {
+	sealed := *review
+	sealed.Highlights = append([]models.ReportHighlight(nil), review.Highlights...)
+	fields := []*string{&sealed.Narrative}
+	for i := range sealed.Highlights {
+		fields = append(fields, &sealed.Highlights[i].Title, &sealed.Highlights[i].Excerpt)
+	}
+	if err := sealFields(enc, review.UserID, fields...); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+func openYearReview(enc *encryption.Encryptor, review *models.YearReview) error {
+	fields := []*string{&review.Narrative}
+	for i := range review.Highlights {
+		fields = append(fields, &review.Highlights[i].Title, &review.Highlights[i].Excerpt)
+	}
+	return openFields(enc, review.UserID, fields...)
+}
+
+// sealTopic returns an encrypted copy of the topic; the original is left as is.
+func sealTopic(enc *encryption.Encryptor, topic *models.Topic) (*models.Topic, error) {
+	sealed := *topic
+	sealed.Terms = append([]string(nil), topic.Terms...)
+	fields := []*string{&sealed.Label}
+	for i := range sealed.Terms {
+		fields = append(fields, &sealed.Terms[i])
+	}
+	if err := sealFields(enc, topic.UserID, fields...); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+func openTopic(enc *encryption.Encryptor, topic *models.Topic) error {
+	fields := []*string{&topic.Label}
+	for i := range topic.Terms {
+		fields = append(fields, &topic.Terms[i])
+	}
+	return openFields(enc, topic.UserID, fields...)
+}
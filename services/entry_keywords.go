@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/keywords"
+	"soulprint-backend/language"
+	"soulprint-backend/models"
+	"soulprint-backend/redact"
+	"soulprint-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// corpusTTL is how long a user's keyword corpus is reused before it is rebuilt from
+// their entries. Writes update it in place; the rebuild catches deletions and edits
+// made by other instances. Rebuilds of a stale corpus run in the background.
+const corpusTTL = time.Hour
+
+// keywordCorpora caches each user's keyword corpus. It is kept in memory only: stored
+// document frequencies would reveal an encrypted journal's vocabulary.
+type keywordCorpora struct {
+	mu      sync.Mutex
+	corpora map[string]*cachedCorpus
+}
+
+type cachedCorpus struct {
+	corpus     *keywords.Corpus
+	builtAt    time.Time
+	rebuilding bool
+}
+
+func newKeywordCorpora() *keywordCorpora {
+	return &keywordCorpora{corpora: make(map[string]*cachedCorpus)}
+}
+
+// entryText is the text keywords are extracted from.
+func entryText(title, content string) string {
+	return title + ".\n" + content
+}
+
+// corpus returns the user's keyword corpus. Only the first call for a user builds it
+// from their entries before returning; once it is stale it keeps being served while a
+// rebuild runs in the background, so writes never wait for a whole journal to load.
+func (js *JournalService) corpus(userID string) (*keywords.Corpus, error) {
+	js.corpora.mu.Lock()
+	cached := js.corpora.corpora[userID]
+	stale := cached != nil && !cached.rebuilding && time.Since(cached.builtAt) >= corpusTTL
+	if stale {
+		cached.rebuilding = true
+	}
+	js.corpora.mu.Unlock()
+
+	if cached != nil {
+		if stale {
+			go func() {
+				if _, err := js.buildCorpus(userID); err != nil {
+					log.Printf("keywords: failed to rebuild corpus for %s: %v", userID, err)
+					js.corpora.mu.Lock()
+					cached.rebuilding = false
+					js.corpora.mu.Unlock()
+				}
+			}()
+		}
+		return cached.corpus, nil
+	}
+	return js.buildCorpus(userID)
+}
+
+// buildCorpus builds the user's corpus from their entries and caches it.
+func (js *JournalService) buildCorpus(userID string) (*keywords.Corpus, error) {
+	entries, err := js.GetEntries(userID)
+	if err != nil {
+		return nil, err
+	}
+	corpus := keywords.NewCorpus()
+	for _, entry := range readableEntries(entries) {
+		corpus.Add(entryText(entry.Title, entry.Content), entry.Language)
+	}
+
+	js.corpora.mu.Lock()
+	js.corpora.corpora[userID] = &cachedCorpus{corpus: corpus, builtAt: time.Now()}
+	js.corpora.mu.Unlock()
+	return corpus, nil
+}
+
+// updateCorpus swaps an entry's old version for its new one in the user's cached
+// corpus, if there is one. Either may be nil; encrypted envelopes are skipped.
+func (js *JournalService) updateCorpus(userID string, removed, added *models.JournalEntry) {
+	js.corpora.mu.Lock()
+	cached := js.corpora.corpora[userID]
+	js.corpora.mu.Unlock()
+	if cached == nil {
+		return
+	}
+	if removed != nil && removed.Envelope == nil {
+		cached.corpus.Remove(entryText(removed.Title, removed.Content), removed.Language)
+	}
+	if added != nil && added.Envelope == nil {
+		cached.corpus.Add(entryText(added.Title, added.Content), added.Language)
+	}
+}
+
+// ExtractKeywords returns the key phrases of a text, weighted against the user's other
+// entries. If the corpus cannot be loaded the phrases are scored on the text alone.
+func (js *JournalService) ExtractKeywords(userID, title, content, lang string) []models.Keyword {
+	corpus, err := js.corpus(userID)
+	if err != nil {
+		log.Printf("keywords: scoring without corpus for %s: %v", userID, err)
+	}
+	extracted := keywords.Extract(entryText(title, content), keywords.Options{Language: lang, Corpus: corpus})
+	result := make([]models.Keyword, len(extracted))
+	for i, keyword := range extracted {
+		result[i] = models.Keyword{Term: keyword.Term, Score: keyword.Score}
+	}
+	return result
+}
+
+// entryKeywords extracts the keywords stored on an entry. End-to-end encrypted entries
+// have none, since the server never sees their text.
+func (js *JournalService) entryKeywords(userID string, entry *models.JournalEntry) []models.Keyword {
+	if entry.Envelope != nil {
+		return nil
+	}
+	return js.ExtractKeywords(userID, entry.Title, entry.Content, entry.Language)
+}
+
+// BackfillKeywords extracts keywords for up to limit entries written before keyword
+// extraction existed, detecting their language on the way, and returns how many it
+// updated.
+func (js *JournalService) BackfillKeywords(ctx context.Context, limit int64) (int, error) {
+	filter := bson.M{"keywords": bson.M{"$exists": false}, "envelope": nil, "deleted_at": nil}
+	cursor, err := js.collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find entries without keywords: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.JournalEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return 0, fmt.Errorf("failed to decode entries without keywords: %w", err)
+	}
+	if err := openEntries(js.encryptor, entries); err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, entry := range entries {
+		set := bson.M{}
+		if entry.Language == "" {
+			entry.Language = language.Detect(entryText(entry.Title, entry.Content)).Code
+			if entry.Language != "" {
+				set["language"] = entry.Language
+			}
+		}
+		entry.Keywords = js.entryKeywords(entry.UserID, &entry)
+		sealed, err := sealEntry(js.encryptor, &entry)
+		if err != nil {
+			return updated, err
+		}
+		set["keywords"] = sealed.Keywords
+		// Only fill in entries that were not rewritten in the meantime.
+		result, err := js.collection.UpdateOne(ctx,
+			bson.M{"_id": entry.ID, "keywords": bson.M{"$exists": false}},
+			bson.M{"$set": set},
+		)
+		if err != nil {
+			return updated, fmt.Errorf("failed to store entry keywords: %w", err)
+		}
+		updated += int(result.MatchedCount)
+	}
+	return updated, nil
+}
+
+// reflectionKeywords returns the keywords listed on a reflection. By default they are
+// the entry's own offline keywords; with KEYWORD_EXTRACTOR=model they come from the
+// model, falling back to the offline ones when the call fails. plaintext is the
+// entry's text before redaction, redacted what the model may see.
+func (ais *AIService) reflectionKeywords(client *utils.OpenAIClient, redactor *redact.Redactor, entry *models.JournalEntry, plaintext, redacted string) []string {
+	if config.AppConfig.KeywordExtractor == "model" {
+		terms, err := client.ExtractKeywords(redacted)
+		if err == nil {
+			for i := range terms {
+				terms[i] = redactor.Restore(terms[i])
+			}
+			if terms = normalizeTerms(terms); len(terms) > 0 {
+				return terms
+			}
+		} else {
+			log.Printf("keywords: model extraction failed for entry %s, using offline keywords: %v", entry.ID.Hex(), err)
+		}
+	}
+
+	extracted := entry.Keywords
+	if extracted == nil || entry.Envelope != nil {
+		extracted = ais.journalService.ExtractKeywords(entry.UserID, entry.Title, plaintext, entryLanguageOf(entry, plaintext))
+	}
+	terms := make([]string, len(extracted))
+	for i, keyword := range extracted {
+		terms[i] = keyword.Term
+	}
+	return terms
+}
+
+// normalizeTerms normalizes model-written keywords and drops empty and repeated ones.
+func normalizeTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, term := range terms {
+		term = keywords.Normalize(term)
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		normalized = append(normalized, term)
+	}
+	return normalized
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"strings"
+
+	"soulprint-backend/language"
+	"soulprint-backend/models"
+)
+
+// entryLanguage returns the language to store on an entry: the one the client gave,
+// otherwise the detected one. End-to-end encrypted entries only have a language if
+// the client sends it.
+func entryLanguage(req models.CreateJournalRequest) string {
+	if req.Language != "" {
+		return language.Normalize(req.Language)
+	}
+	if req.Envelope != nil {
+		return ""
+	}
+	return language.Detect(entryText(req.Title, req.Content)).Code
+}
+
+// entryLanguageOf returns an entry's language, detecting it from text when none is
+// stored, as for entries written before detection or end-to-end encrypted ones.
+func entryLanguageOf(entry *models.JournalEntry, text string) string {
+	if entry.Language != "" {
+		return entry.Language
+	}
+	return language.Detect(entryText(entry.Title, text)).Code
+}
+
+// reflectionLanguage returns the language a reflection on the entry is written in:
+// the user's reflection language if they set one, otherwise the entry's. Empty means
+// the model's default.
+func (ais *AIService) reflectionLanguage(userID string, entry *models.JournalEntry, text string) (string, error) {
+	settings, err := ais.journalService.settings.GetSettings(userID)
+	if err != nil {
+		return "", err
+	}
+	if settings.ReflectionLanguage != "" {
+		return settings.ReflectionLanguage, nil
+	}
+	return entryLanguageOf(entry, text), nil
+}
+
+// digestLanguage returns the language a digest is written in: the user's reflection
+// language if they set one, otherwise the one most of the entries are written in.
+func (ais *AIService) digestLanguage(userID string, entries []models.JournalEntry) (string, error) {
+	settings, err := ais.journalService.settings.GetSettings(userID)
+	if err != nil {
+		return "", err
+	}
+	if settings.ReflectionLanguage != "" {
+		return settings.ReflectionLanguage, nil
+	}
+	counts := make(map[string]int)
+	best := ""
+	for i := range entries {
+		lang := entryLanguageOf(&entries[i], entries[i].Content)
+		counts[lang]++
+		if counts[lang] > counts[best] || counts[lang] == counts[best] && lang < best {
+			best = lang
+		}
+	}
+	return best, nil
+}
+
+// sentimentLexicons hold, per language, words and word stems that signal a positive
+// or negative mood. Stems match inflected forms ("preocupad" matches "preocupada").
+var sentimentLexicons = map[string]struct{ positive, negative []string }{
+	"en": {
+		positive: []string{"happy", "joy", "grateful", "excited", "love", "wonderful", "amazing", "great"},
+		negative: []string{"sad", "angry", "frustrated", "worried", "anxious", "terrible", "awful", "horrible"},
+	},
+	"es": {
+		positive: []string{"feliz", "alegr", "agradecid", "emocionad", "encant", "maravill", "genial", "amor"},
+		negative: []string{"triste", "enfadad", "frustrad", "preocupad", "ansios", "terrible", "horrible", "fatal"},
+	},
+	"fr": {
+		positive: []string{"heureu", "joie", "reconnaissant", "ravi", "adore", "merveill", "génial", "amour"},
+		negative: []string{"triste", "colère", "frustré", "inquiet", "anxi", "terrible", "horrible", "affreu"},
+	},
+	"de": {
+		positive: []string{"glücklich", "freude", "dankbar", "begeistert", "liebe", "wunderbar", "toll", "großartig"},
+		negative: []string{"traurig", "wütend", "frustriert", "besorgt", "ängstlich", "schrecklich", "furchtbar", "angst"},
+	},
+	"pt": {
+		positive: []string{"feliz", "alegr", "gratidão", "grat", "animad", "maravilh", "ótimo", "amor"},
+		negative: []string{"triste", "raiva", "frustrad", "preocupad", "ansios", "terrível", "horrível", "péssim"},
+	},
+	"it": {
+		positive: []string{"felice", "gioia", "gratitudine", "entusiast", "amore", "meraviglios", "fantastic", "ottim"},
+		negative: []string{"triste", "arrabbiat", "frustrat", "preoccupat", "ansios", "terribile", "orribile", "pessim"},
+	},
+	"nl": {
+		positive: []string{"blij", "vreugde", "dankbaar", "enthousiast", "liefde", "geweldig", "fantastisch", "prachtig"},
+		negative: []string{"verdrietig", "boos", "gefrustreerd", "bezorgd", "angstig", "verschrikkelijk", "vreselijk", "afschuwelijk"},
+	},
+}
+
+// sentimentLexicon returns the lexicon for a language, detecting the language of
+// content when it is not given. Languages without a lexicon use English.
+func sentimentLexicon(content, lang string) (positive, negative []string) {
+	if lang == "" {
+		lang = language.Detect(content).Code
+	}
+	lexicon, ok := sentimentLexicons[language.Normalize(lang)]
+	if !ok {
+		lexicon = sentimentLexicons["en"]
+	}
+	return lexicon.positive, lexicon.negative
+}
+
+// countMatches counts the words that occur in content, which must be lowercase.
+func countMatches(content string, words []string) int {
+	count := 0
+	for _, word := range words {
+		if strings.Contains(content, word) {
+			count++
+		}
+	}
+	return count
+}
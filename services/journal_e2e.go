@@ -0,0 +1,76 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"soulprint-backend/language"
+	"soulprint-backend/models"
+)
+
+// EnvelopeAlgorithms are the client-side ciphers accepted for encrypted envelopes.
+var EnvelopeAlgorithms = map[string]bool{
+	"AES-256-GCM":        true,
+	"XChaCha20-Poly1305": true,
+}
+
+// validateEntry checks a create or update request against the user's journal mode.
+// End-to-end encrypted journals take content only as an envelope (the title is
+// optional, clients that want it private put it inside the envelope); regular
+// journals take plaintext only.
+func (js *JournalService) validateEntry(userID string, req *models.CreateJournalRequest) error {
+	if req.Language != "" && !language.Supported(req.Language) {
+		return fmt.Errorf("invalid entry: unsupported language %q", req.Language)
+	}
+
+	e2e, err := js.settings.E2EEnabled(userID)
+	if err != nil {
+		return err
+	}
+
+	if !e2e {
+		if req.Envelope != nil {
+			return fmt.Errorf("invalid entry: enable end-to-end encryption before sending envelopes")
+		}
+		if req.Title == "" || req.Content == "" {
+			return fmt.Errorf("invalid entry: title and content are required")
+		}
+		return nil
+	}
+
+	if req.Envelope == nil {
+		return fmt.Errorf("invalid entry: end-to-end encrypted journals only accept an encrypted envelope")
+	}
+	if req.Content != "" {
+		return fmt.Errorf("invalid entry: content must be sent inside the envelope")
+	}
+	return validateEnvelope(req.Envelope)
+}
+
+func validateEnvelope(envelope *models.EncryptedEnvelope) error {
+	if !EnvelopeAlgorithms[envelope.Algorithm] {
+		return fmt.Errorf("invalid envelope: unsupported algorithm %q", envelope.Algorithm)
+	}
+	if envelope.KeyID == "" {
+		return fmt.Errorf("invalid envelope: key_id is required")
+	}
+	if ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext); err != nil || len(ciphertext) == 0 {
+		return fmt.Errorf("invalid envelope: ciphertext must be non-empty base64")
+	}
+	if nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce); err != nil || len(nonce) == 0 {
+		return fmt.Errorf("invalid envelope: nonce must be non-empty base64")
+	}
+	return nil
+}
+
+// readableEntries drops entries the server cannot read because their content is an
+// end-to-end encrypted envelope.
+func readableEntries(entries []models.JournalEntry) []models.JournalEntry {
+	readable := entries[:0:0]
+	for _, entry := range entries {
+		if entry.Envelope == nil {
+			readable = append(readable, entry)
+		}
+	}
+	return readable
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+
+	"soulprint-backend/models"
+	"soulprint-backend/utils"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchEntry applies a JSON Merge Patch or JSON Patch to the editable fields of an
+// entry (title, content or envelope, tags, mood, prompt and template answers). The
+// patch is applied to the version it was read from, so concurrent edits are never
+// overwritten: with expectedVersion the caller's version must still be current,
+// otherwise the patch is re-applied to the newer entry.
+func (js *JournalService) PatchEntry(userID, entryID, contentType string, patch []byte, expectedVersion *int64) (*models.JournalEntry, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported patch content type %q", contentType)
+	}
+	apply := utils.ApplyMergePatch
+	switch mediaType {
+	case MergePatchContentType, "application/json":
+	case JSONPatchContentType:
+		apply = utils.ApplyJSONPatch
+	default:
+		return nil, fmt.Errorf("unsupported patch content type %q", mediaType)
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		current, err := js.GetEntryByID(userID, entryID)
+		if err != nil {
+			return nil, err
+		}
+		if expectedVersion != nil && *expectedVersion != current.Version {
+			return nil, fmt.Errorf("journal entry version mismatch")
+		}
+
+		doc, err := json.Marshal(patchView(current))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode journal entry: %w", err)
+		}
+		patched, err := apply(doc, patch)
+		if err != nil {
+			return nil, err
+		}
+
+		var req models.CreateJournalRequest
+		decoder := json.NewDecoder(bytes.NewReader(patched))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			return nil, fmt.Errorf("invalid patch result: %w", err)
+		}
+		updated := &models.JournalEntry{Title: req.Title, Content: req.Content, Envelope: req.Envelope, Tags: req.Tags, Mood: req.Mood,
+			PromptID: req.PromptID, TemplateID: req.TemplateID, Answers: req.Answers}
+		if reflect.DeepEqual(patchView(current), patchView(updated)) {
+			return current, nil
+		}
+
+		entry, err := js.UpdateEntry(userID, entryID, req, &current.Version)
+		if err == nil || expectedVersion != nil || err.Error() != "journal entry version mismatch" {
+			return entry, err
+		}
+		// Someone else wrote in between; apply the patch to their version.
+	}
+	return nil, fmt.Errorf("failed to patch journal entry: too many concurrent updates")
+}
+
+// patchView is the part of an entry that patches operate on. Tags, and the answers of
+// templated entries, are always arrays so that operations like "add /tags/-" work on
+// entries without any.
+func patchView(entry *models.JournalEntry) map[string]interface{} {
+	tags := entry.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	view := map[string]interface{}{
+		"title":   entry.Title,
+		"content": entry.Content,
+		"tags":    tags,
+		"mood":    entry.Mood,
+	}
+	if entry.Envelope != nil {
+		view["envelope"] = *entry.Envelope
+	}
+	if entry.PromptID != "" {
+		view["prompt_id"] = entry.PromptID
+	}
+	if entry.TemplateID != "" {
+		answers := entry.Answers
+		if answers == nil {
+			answers = []models.FieldAnswer{}
+		}
+		view["template_id"] = entry.TemplateID
+		view["answers"] = answers
+	}
+	return view
+}
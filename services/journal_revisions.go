@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"soulprint-backend/models"
+	"soulprint-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordRevision stores the pre-update state of an entry as the given revision number.
+// Revisions are numbered from 1 (the entry as first written); the live entry is always
+// one past the last revision. It returns the ID of the stored revision so that it can be
+// removed again if the update does not go through.
+func (js *JournalService) recordRevision(previous *models.JournalEntry, number int, replacedAt time.Time) (primitive.ObjectID, error) {
+	revision := models.EntryRevision{
+		ID:         primitive.NewObjectID(),
+		EntryID:    previous.ID,
+		UserID:     previous.UserID,
+		Revision:   number,
+		Title:      previous.Title,
+		Content:    previous.Content,
+		Envelope:   previous.Envelope,
+		Tags:       previous.Tags,
+		Mood:       previous.Mood,
+		PromptID:   previous.PromptID,
+		TemplateID: previous.TemplateID,
+		Answers:    append([]models.FieldAnswer(nil), previous.Answers...),
+		EditedAt:   previous.UpdatedAt,
+		CreatedAt:  replacedAt,
+	}
+	if err := sealFields(js.encryptor, revision.UserID, revisionFields(&revision)...); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	if _, err := js.revisions.InsertOne(context.Background(), revision); err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to record entry revision: %w", err)
+	}
+	return revision.ID, nil
+}
+
+// revisionCount returns how many revisions of an entry are stored. Entries last edited
+// before the per-entry counter existed are counted once; their next update sets the
+// counter.
+func (js *JournalService) revisionCount(entry *models.JournalEntry) (int, error) {
+	if entry.RevisionCount > 0 {
+		return entry.RevisionCount, nil
+	}
+	count, err := js.revisions.CountDocuments(context.Background(), bson.M{"entry_id": entry.ID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count entry revisions: %w", err)
+	}
+	return int(count), nil
+}
+
+// GetRevisions lists an entry's previous versions, oldest first.
+func (js *JournalService) GetRevisions(userID, entryID string) ([]models.EntryRevision, error) {
+	entry, err := js.GetEntryByID(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cursor, err := js.revisions.Find(context.Background(), bson.M{"entry_id": entry.ID, "user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry revisions: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var revisions []models.EntryRevision
+	if err = cursor.All(context.Background(), &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode entry revisions: %w", err)
+	}
+	for i := range revisions {
+		if err := openFields(js.encryptor, userID, revisionFields(&revisions[i])...); err != nil {
+			return nil, err
+		}
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns one version of an entry. "current" (or the number after the
+// last stored revision) returns the live entry as a revision.
+func (js *JournalService) GetRevision(userID, entryID, revision string) (*models.EntryRevision, error) {
+	entry, err := js.GetEntryByID(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := js.revisionCount(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	number := count + 1
+	if revision != "current" {
+		number, err = strconv.Atoi(revision)
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("invalid revision %q", revision)
+		}
+	}
+
+	if number == count+1 {
+		return &models.EntryRevision{
+			EntryID:    entry.ID,
+			UserID:     entry.UserID,
+			Revision:   number,
+			Title:      entry.Title,
+			Content:    entry.Content,
+			Envelope:   entry.Envelope,
+			Tags:       entry.Tags,
+			Mood:       entry.Mood,
+			PromptID:   entry.PromptID,
+			TemplateID: entry.TemplateID,
+			Answers:    entry.Answers,
+			EditedAt:   entry.UpdatedAt,
+			CreatedAt:  entry.UpdatedAt,
+		}, nil
+	}
+
+	var stored models.EntryRevision
+	err = js.revisions.FindOne(context.Background(), bson.M{"entry_id": entry.ID, "user_id": userID, "revision": number}).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("revision not found")
+		}
+		return nil, fmt.Errorf("failed to find entry revision: %w", err)
+	}
+	if err := openFields(js.encryptor, userID, revisionFields(&stored)...); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+// DiffRevisions compares two versions of an entry word by word.
+func (js *JournalService) DiffRevisions(userID, entryID, from, to string) (*models.RevisionDiff, error) {
+	older, err := js.GetRevision(userID, entryID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := js.GetRevision(userID, entryID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.RevisionDiff{
+		From:    strconv.Itoa(older.Revision),
+		To:      strconv.Itoa(newer.Revision),
+		Title:   utils.DiffWords(older.Title, newer.Title),
+		Content: utils.DiffWords(older.Content, newer.Content),
+	}
+	diff.WordsAdded, diff.WordsRemoved = utils.DiffStats(diff.Content)
+	diff.TagsAdded, diff.TagsRemoved = diffTags(older.Tags, newer.Tags)
+	if older.Mood != newer.Mood {
+		diff.MoodFrom = older.Mood
+		diff.MoodTo = newer.Mood
+	}
+
+	return diff, nil
+}
+
+// RestoreRevision makes an older version the live entry again. The version being
+// replaced is itself kept as a new revision, so a restore can be undone.
+func (js *JournalService) RestoreRevision(userID, entryID, revision string, expectedVersion *int64) (*models.JournalEntry, error) {
+	target, err := js.GetRevision(userID, entryID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return js.UpdateEntry(userID, entryID, models.CreateJournalRequest{
+		Title:      target.Title,
+		Content:    target.Content,
+		Envelope:   target.Envelope,
+		Tags:       target.Tags,
+		Mood:       target.Mood,
+		PromptID:   target.PromptID,
+		TemplateID: target.TemplateID,
+		Answers:    target.Answers,
+	}, expectedVersion)
+}
+
+func diffTags(from, to []string) (added, removed []string) {
+	before := make(map[string]bool, len(from))
+	for _, tag := range from {
+		before[tag] = true
+	}
+	after := make(map[string]bool, len(to))
+	for _, tag := range to {
+		after[tag] = true
+		if !before[tag] {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range from {
+		if !after[tag] {
+			removed = append(removed, tag)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
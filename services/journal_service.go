@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"soulprint-backend/config"
+	"soulprint-backend/encryption"
 	"soulprint-backend/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,40 +17,150 @@ import (
 )
 
 type JournalService struct {
-	client     *mongo.Client
-	collection *mongo.Collection
+	client      *mongo.Client
+	collection  *mongo.Collection
+	revisions   *mongo.Collection
+	reflections *mongo.Collection
+	feedback    *mongo.Collection
+	topics      *mongo.Collection
+	safety      *mongo.Collection
+	webhooks    *WebhookService
+	encryptor   *encryption.Encryptor
+	settings    *SettingsService
+	templates   *TemplateService
+	corpora     *keywordCorpora
 }
 
-func NewJournalService(client *mongo.Client) *JournalService {
-	collection := client.Database(config.AppConfig.MongoDatabase).Collection("journal_entries")
+func NewJournalService(client *mongo.Client, webhooks *WebhookService, encryptor *encryption.Encryptor, settings *SettingsService, templates *TemplateService) *JournalService {
+	db := client.Database(config.AppConfig.MongoDatabase)
 	return &JournalService{
-		client:     client,
-		collection: collection,
+		client:      client,
+		collection:  db.Collection("journal_entries"),
+		revisions:   db.Collection("journal_entry_revisions"),
+		reflections: db.Collection("reflections"),
+		feedback:    db.Collection("reflection_feedback"),
+		topics:      db.Collection("topics"),
+		safety:      db.Collection("safety_events"),
+		webhooks:    webhooks,
+		encryptor:   encryptor,
+		settings:    settings,
+		templates:   templates,
+		corpora:     newKeywordCorpora(),
 	}
 }
 
+// EnsureIndexes creates the indexes used by entry queries.
+func (js *JournalService) EnsureIndexes(ctx context.Context) error {
+	if _, err := js.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	}); err != nil {
+		return err
+	}
+	_, err := js.revisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "entry_id", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
 func (js *JournalService) CreateEntry(userID string, req models.CreateJournalRequest) (*models.JournalEntry, error) {
+	if err := js.templates.fillEntry(userID, &req); err != nil {
+		return nil, err
+	}
+	if err := js.validateEntry(userID, &req); err != nil {
+		return nil, err
+	}
+
 	entry := &models.JournalEntry{
-		UserID:    userID,
-		Title:     req.Title,
-		Content:   req.Content,
-		Tags:      req.Tags,
-		Mood:      req.Mood,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		UserID:     userID,
+		Title:      req.Title,
+		Content:    req.Content,
+		Envelope:   req.Envelope,
+		Tags:       req.Tags,
+		Mood:       req.Mood,
+		Language:   entryLanguage(req),
+		PromptID:   req.PromptID,
+		TemplateID: req.TemplateID,
+		Answers:    req.Answers,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Version:    1,
+	}
+	entry.Keywords = js.entryKeywords(userID, entry)
+
+	// Store an encrypted copy; callers get the plaintext entry back.
+	stored, err := sealEntry(js.encryptor, entry)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := js.collection.InsertOne(context.Background(), entry)
+	result, err := js.collection.InsertOne(context.Background(), stored)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create journal entry: %w", err)
 	}
 
 	entry.ID = result.InsertedID.(primitive.ObjectID)
+	js.updateCorpus(userID, nil, entry)
+	js.webhooks.Publish(userID, EventEntryCreated, entry)
 	return entry, nil
 }
 
 func (js *JournalService) GetEntries(userID string) ([]models.JournalEntry, error) {
-	filter := bson.M{"user_id": userID}
+	filter := bson.M{"user_id": userID, "deleted_at": nil}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := js.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find journal entries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []models.JournalEntry
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode journal entries: %w", err)
+	}
+	if err := openEntries(js.encryptor, entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetEntriesInRange returns a user's entries created in [from, to), oldest first.
+func (js *JournalService) GetEntriesInRange(userID string, from, to time.Time) ([]models.JournalEntry, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$gte": from, "$lt": to},
+		"deleted_at": nil,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := js.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find journal entries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []models.JournalEntry
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode journal entries: %w", err)
+	}
+	if err := openEntries(js.encryptor, entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetEntriesByIDs returns the user's entries with the given IDs that are not in the
+// trash, newest first.
+func (js *JournalService) GetEntriesByIDs(userID string, entryIDs []primitive.ObjectID) ([]models.JournalEntry, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$in": entryIDs},
+		"user_id":    userID,
+		"deleted_at": nil,
+	}
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 
 	cursor, err := js.collection.Find(context.Background(), filter, opts)
@@ -61,10 +173,44 @@ func (js *JournalService) GetEntries(userID string) ([]models.JournalEntry, erro
 	if err = cursor.All(context.Background(), &entries); err != nil {
 		return nil, fmt.Errorf("failed to decode journal entries: %w", err)
 	}
+	if err := openEntries(js.encryptor, entries); err != nil {
+		return nil, err
+	}
 
 	return entries, nil
 }
 
+// HasEntrySince reports whether the user created an entry at or after since.
+func (js *JournalService) HasEntrySince(userID string, since time.Time) (bool, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"created_at": bson.M{"$gte": since},
+		"deleted_at": nil,
+	}
+	count, err := js.collection.CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to count journal entries: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetUserIDs returns every user that has written at least one entry.
+func (js *JournalService) GetUserIDs() ([]string, error) {
+	values, err := js.collection.Distinct(context.Background(), "user_id", bson.M{"deleted_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	userIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if userID, ok := value.(string); ok && userID != "" {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return userIDs, nil
+}
+
 func (js *JournalService) GetEntryByID(userID, entryID string) (*models.JournalEntry, error) {
 	objectID, err := primitive.ObjectIDFromHex(entryID)
 	if err != nil {
@@ -72,8 +218,9 @@ func (js *JournalService) GetEntryByID(userID, entryID string) (*models.JournalE
 	}
 
 	filter := bson.M{
-		"_id":     objectID,
-		"user_id": userID,
+		"_id":        objectID,
+		"user_id":    userID,
+		"deleted_at": nil,
 	}
 
 	var entry models.JournalEntry
@@ -84,63 +231,230 @@ func (js *JournalService) GetEntryByID(userID, entryID string) (*models.JournalE
 		}
 		return nil, fmt.Errorf("failed to find journal entry: %w", err)
 	}
+	if err := openEntry(js.encryptor, &entry); err != nil {
+		return nil, err
+	}
 
 	return &entry, nil
 }
 
-func (js *JournalService) UpdateEntry(userID, entryID string, req models.CreateJournalRequest) (*models.JournalEntry, error) {
+// UpdateEntry replaces an entry's fields. When expectedVersion is set the update only
+// applies if the entry is still at that version.
+func (js *JournalService) UpdateEntry(userID, entryID string, req models.CreateJournalRequest, expectedVersion *int64) (*models.JournalEntry, error) {
 	objectID, err := primitive.ObjectIDFromHex(entryID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid entry ID: %w", err)
 	}
+	if err := js.templates.fillEntry(userID, &req); err != nil {
+		return nil, err
+	}
+	if err := js.validateEntry(userID, &req); err != nil {
+		return nil, err
+	}
 
 	filter := bson.M{
-		"_id":     objectID,
-		"user_id": userID,
+		"_id":        objectID,
+		"user_id":    userID,
+		"deleted_at": nil,
 	}
+	matchVersion(filter, expectedVersion)
 
-	update := bson.M{
-		"$set": bson.M{
-			"title":      req.Title,
-			"content":    req.Content,
-			"tags":       req.Tags,
-			"mood":       req.Mood,
-			"updated_at": time.Now(),
-		},
+	updated := &models.JournalEntry{
+		UserID:   userID,
+		Title:    req.Title,
+		Content:  req.Content,
+		Envelope: req.Envelope,
+		Language: entryLanguage(req),
+		Answers:  req.Answers,
+	}
+	updated.Keywords = js.entryKeywords(userID, updated)
+	sealed, err := sealEntry(js.encryptor, updated)
+	if err != nil {
+		return nil, err
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var entry models.JournalEntry
-	err = js.collection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&entry)
+	now := time.Now()
+	set := bson.M{
+		"title":      sealed.Title,
+		"content":    sealed.Content,
+		"keywords":   sealed.Keywords,
+		"tags":       req.Tags,
+		"mood":       req.Mood,
+		"updated_at": now,
+	}
+	inc := bson.M{"version": 1}
+	update := bson.M{"$set": set, "$inc": inc}
+	unset := bson.M{}
+	if req.Envelope != nil {
+		set["envelope"] = req.Envelope
+	} else {
+		unset["envelope"] = ""
+	}
+	for field, value := range map[string]string{"language": updated.Language, "prompt_id": req.PromptID, "template_id": req.TemplateID} {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	if len(sealed.Answers) > 0 {
+		set["answers"] = sealed.Answers
+	} else {
+		unset["answers"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	previous, err := js.replaceEntry(userID, entryID, filter, update, expectedVersion, now)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("journal entry not found")
+		return nil, err
+	}
+	if previous.Title != req.Title || previous.Content != req.Content {
+		if err := js.invalidateReflectionCache(objectID); err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to update journal entry: %w", err)
 	}
 
+	entry := *previous
+	entry.Title = req.Title
+	entry.Content = req.Content
+	entry.Language = updated.Language
+	entry.PromptID = req.PromptID
+	entry.TemplateID = req.TemplateID
+	entry.Answers = req.Answers
+	entry.Keywords = updated.Keywords
+	entry.Envelope = req.Envelope
+	entry.Tags = req.Tags
+	entry.Mood = req.Mood
+	entry.UpdatedAt = now
+	entry.Version++
+	if previous.Title != req.Title || previous.Content != req.Content {
+		js.updateCorpus(userID, previous, &entry)
+	}
+
+	js.webhooks.Publish(userID, EventEntryUpdated, &entry)
 	return &entry, nil
 }
 
-func (js *JournalService) DeleteEntry(userID, entryID string) error {
+// DeleteEntry moves an entry and its reflections to the trash. They are purged for good
+// once the trash retention period has passed. expectedVersion works as in UpdateEntry.
+func (js *JournalService) DeleteEntry(userID, entryID string, expectedVersion *int64) error {
 	objectID, err := primitive.ObjectIDFromHex(entryID)
 	if err != nil {
 		return fmt.Errorf("invalid entry ID: %w", err)
 	}
 
 	filter := bson.M{
-		"_id":     objectID,
-		"user_id": userID,
+		"_id":        objectID,
+		"user_id":    userID,
+		"deleted_at": nil,
 	}
+	matchVersion(filter, expectedVersion)
 
-	result, err := js.collection.DeleteOne(context.Background(), filter)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"deleted_at": now}, "$inc": bson.M{"version": 1}}
+	result, err := js.collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to delete journal entry: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("journal entry not found")
+	if result.MatchedCount == 0 {
+		return js.missError(userID, entryID, expectedVersion)
 	}
 
+	// Reflections share the entry's deletion timestamp so a restore brings back exactly
+	// the ones that were trashed with it.
+	_, err = js.reflections.UpdateMany(context.Background(),
+		bson.M{"entry_id": objectID, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete entry reflections: %w", err)
+	}
+
+	js.webhooks.Publish(userID, EventEntryDeleted, map[string]interface{}{"id": entryID})
 	return nil
-} 
\ No newline at end of file
+}
+
+// replaceEntry applies update to the entry matching filter and returns the entry as it
+// was before. The previous state is stored as a revision first, and the update is then
+// made conditional on the entry's version, so an entry is never changed without its
+// revision. If a concurrent write gets in between, the revision is removed and the
+// whole step is retried.
+func (js *JournalService) replaceEntry(userID, entryID string, filter, update bson.M, expectedVersion *int64, now time.Time) (*models.JournalEntry, error) {
+	set, inc := update["$set"].(bson.M), update["$inc"].(bson.M)
+	for attempt := 0; attempt < 3; attempt++ {
+		var previous models.JournalEntry
+		if err := js.collection.FindOne(context.Background(), filter).Decode(&previous); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, js.missError(userID, entryID, expectedVersion)
+			}
+			return nil, fmt.Errorf("failed to find journal entry: %w", err)
+		}
+		if err := openEntry(js.encryptor, &previous); err != nil {
+			return nil, err
+		}
+
+		count, err := js.revisionCount(&previous)
+		if err != nil {
+			return nil, err
+		}
+		revisionID, err := js.recordRevision(&previous, count+1, now)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				// A concurrent update took this number.
+				continue
+			}
+			return nil, err
+		}
+
+		delete(set, "revision_count")
+		delete(inc, "revision_count")
+		if previous.RevisionCount > 0 {
+			inc["revision_count"] = 1
+		} else {
+			set["revision_count"] = count + 1
+		}
+		guarded := bson.M{"_id": previous.ID, "user_id": userID, "deleted_at": nil}
+		matchVersion(guarded, &previous.Version)
+
+		result, err := js.collection.UpdateOne(context.Background(), guarded, update)
+		if err == nil && result.MatchedCount == 1 {
+			return &previous, nil
+		}
+		if _, delErr := js.revisions.DeleteOne(context.Background(), bson.M{"_id": revisionID}); delErr != nil {
+			log.Printf("journal: failed to remove revision %s of entry %s: %v", revisionID.Hex(), entryID, delErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update journal entry: %w", err)
+		}
+		// The entry changed since it was read; read it again.
+	}
+	return nil, fmt.Errorf("failed to update journal entry: too many concurrent updates")
+}
+
+// matchVersion restricts a write filter to the expected entry version, if any.
+func matchVersion(filter bson.M, expectedVersion *int64) {
+	if expectedVersion == nil {
+		return
+	}
+	if *expectedVersion == 0 {
+		// Entries from before versioning have no version field at all.
+		filter["version"] = bson.M{"$in": bson.A{int64(0), nil}}
+		return
+	}
+	filter["version"] = *expectedVersion
+}
+
+// missError explains why a conditional write matched nothing: either the entry is
+// gone or it has moved on to another version.
+func (js *JournalService) missError(userID, entryID string, expectedVersion *int64) error {
+	if expectedVersion == nil {
+		return fmt.Errorf("journal entry not found")
+	}
+	if _, err := js.GetEntryByID(userID, entryID); err != nil {
+		return err
+	}
+	return fmt.Errorf("journal entry version mismatch")
+}
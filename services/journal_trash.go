@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTrash lists a user's deleted entries, most recently deleted first.
+func (js *JournalService) GetTrash(userID string) ([]models.TrashedEntry, error) {
+	filter := bson.M{"user_id": userID, "deleted_at": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+
+	cursor, err := js.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted entries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []models.TrashedEntry
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode deleted entries: %w", err)
+	}
+
+	for i := range entries {
+		if err := openEntry(js.encryptor, &entries[i].JournalEntry); err != nil {
+			return nil, err
+		}
+		entries[i].PurgeAt = entries[i].DeletedAt.Add(config.AppConfig.TrashRetention)
+	}
+	return entries, nil
+}
+
+// RestoreEntry takes an entry out of the trash along with the reflections that were
+// deleted with it.
+func (js *JournalService) RestoreEntry(userID, entryID string) (*models.JournalEntry, error) {
+	entry, err := js.getTrashedEntry(userID, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := js.collection.UpdateOne(context.Background(),
+		bson.M{"_id": entry.ID, "deleted_at": entry.DeletedAt},
+		bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore journal entry: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		// Restored or purged since it was read.
+		return nil, fmt.Errorf("journal entry not found")
+	}
+
+	_, err = js.reflections.UpdateMany(context.Background(),
+		bson.M{"entry_id": entry.ID, "deleted_at": entry.DeletedAt},
+		bson.M{"$unset": bson.M{"deleted_at": ""}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore entry reflections: %w", err)
+	}
+
+	entry.DeletedAt = nil
+	entry.Version++
+	js.webhooks.Publish(userID, EventEntryUpdated, entry)
+	return entry, nil
+}
+
+// PurgeEntry permanently deletes a trashed entry with everything derived from it: its
+// reflections, feedback, revisions and safety events, the webhook deliveries that carry
+// it, the digests that cover it and its topic memberships.
+func (js *JournalService) PurgeEntry(userID, entryID string) error {
+	entry, err := js.getTrashedEntry(userID, entryID)
+	if err != nil {
+		return err
+	}
+	return js.purge([]primitive.ObjectID{entry.ID})
+}
+
+// PurgeExpired permanently deletes entries that have been in the trash longer than
+// the retention period. It returns how many entries were purged.
+func (js *JournalService) PurgeExpired(ctx context.Context, retention time.Duration) (int, error) {
+	filter := bson.M{"deleted_at": bson.M{"$ne": nil, "$lt": time.Now().Add(-retention)}}
+	cursor, err := js.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &expired); err != nil {
+		return 0, fmt.Errorf("failed to decode expired entries: %w", err)
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(expired))
+	for i, doc := range expired {
+		ids[i] = doc.ID
+	}
+	return len(ids), js.purge(ids)
+}
+
+func (js *JournalService) purge(entryIDs []primitive.ObjectID) error {
+	filter := bson.M{"entry_id": bson.M{"$in": entryIDs}}
+	if _, err := js.reflections.DeleteMany(context.Background(), filter); err != nil {
+		return fmt.Errorf("failed to purge entry reflections: %w", err)
+	}
+	if _, err := js.feedback.DeleteMany(context.Background(), filter); err != nil {
+		return fmt.Errorf("failed to purge reflection feedback: %w", err)
+	}
+	if _, err := js.revisions.DeleteMany(context.Background(), filter); err != nil {
+		return fmt.Errorf("failed to purge entry revisions: %w", err)
+	}
+	if _, err := js.safety.DeleteMany(context.Background(), filter); err != nil {
+		return fmt.Errorf("failed to purge safety events: %w", err)
+	}
+	// Entry and reflection webhooks carry the text, so their delivery log goes too.
+	if err := js.webhooks.DeleteEntryDeliveries(entryIDs); err != nil {
+		return err
+	}
+	// A digest's text is written from all of its entries, so digests covering a purged
+	// entry go too; they can be generated again from the remaining entries.
+	if _, err := js.reflections.DeleteMany(context.Background(), bson.M{"entry_ids": bson.M{"$in": entryIDs}}); err != nil {
+		return fmt.Errorf("failed to purge digests: %w", err)
+	}
+	// Topics keep their other entries until the next refresh; the size follows.
+	_, err := js.topics.UpdateMany(context.Background(), bson.M{"entry_ids": bson.M{"$in": entryIDs}}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"entry_ids": bson.M{"$filter": bson.M{
+			"input": "$entry_ids",
+			"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this", entryIDs}}}},
+		}}}}},
+		{{Key: "$set", Value: bson.M{"size": bson.M{"$size": "$entry_ids"}}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove purged entries from topics: %w", err)
+	}
+	if _, err := js.collection.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": entryIDs}}); err != nil {
+		return fmt.Errorf("failed to purge journal entries: %w", err)
+	}
+	return nil
+}
+
+func (js *JournalService) getTrashedEntry(userID, entryID string) (*models.JournalEntry, error) {
+	objectID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entry ID: %w", err)
+	}
+
+	filter := bson.M{
+		"_id":        objectID,
+		"user_id":    userID,
+		"deleted_at": bson.M{"$ne": nil},
+	}
+
+	var entry models.JournalEntry
+	err = js.collection.FindOne(context.Background(), filter).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("journal entry not found")
+		}
+		return nil, fmt.Errorf("failed to find journal entry: %w", err)
+	}
+	if err := openEntry(js.encryptor, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
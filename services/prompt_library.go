@@ -0,0 +1,109 @@
+package services
+
+import "soulprint-backend/models"
+
+// PromptCategories are the categories of the built-in prompt library. "theme" prompts
+// contain a {theme} placeholder and are only suggested with one of the user's recent
+// themes filled in.
+var PromptCategories = []string{"daily", "gratitude", "cbt", "morning_pages", "theme"}
+
+// promptLibrary is the built-in prompt library, grouped by category.
+var promptLibrary = []models.Prompt{
+	{ID: "daily-check-in", Category: "daily", Text: "Check in with yourself: how are you, really?", Template: "daily-check-in"},
+	{ID: "daily-highlight", Category: "daily", Text: "What was the best part of today, and why did it stand out?"},
+	{ID: "daily-energy", Category: "daily", Text: "What gave you energy today, and what drained it?"},
+	{ID: "daily-surprise", Category: "daily", Text: "What surprised you today?"},
+	{ID: "daily-learned", Category: "daily", Text: "What did you learn today, about the world or about yourself?"},
+	{ID: "daily-conversation", Category: "daily", Text: "Which conversation from today is still on your mind?"},
+	{ID: "daily-tomorrow", Category: "daily", Text: "What is one thing you want tomorrow to hold?"},
+
+	{ID: "gratitude-three", Category: "gratitude", Text: "Write down three things you are grateful for today.", Template: "gratitude-list"},
+	{ID: "gratitude-person", Category: "gratitude", Text: "Who made your life a little easier recently? What would you tell them?"},
+	{ID: "gratitude-small", Category: "gratitude", Text: "What small, ordinary thing did you enjoy today?"},
+	{ID: "gratitude-past-self", Category: "gratitude", Text: "What did your past self do that you are thankful for now?"},
+	{ID: "gratitude-hard-times", Category: "gratitude", Text: "What has a difficult time taught you that you are grateful for?"},
+
+	{ID: "cbt-thought-record", Category: "cbt", Text: "Pick a moment when your mood dropped and walk through it as a thought record.", Template: "thought-record"},
+	{ID: "cbt-evidence", Category: "cbt", Text: "Which thought has been bothering you? What supports it, and what doesn't?"},
+	{ID: "cbt-friend", Category: "cbt", Text: "If a close friend were in your situation, what would you tell them?"},
+	{ID: "cbt-outcomes", Category: "cbt", Text: "What are you worried about? Describe the worst, the best and the most likely outcome."},
+	{ID: "cbt-control", Category: "cbt", Text: "Which part of what is bothering you is in your control, and which part isn't?"},
+
+	{ID: "morning-pages", Category: "morning_pages", Text: "Write three pages of whatever comes to mind. Don't edit and don't stop.", Template: "morning-pages"},
+	{ID: "morning-intention", Category: "morning_pages", Text: "What do you want to focus on today, and what might get in the way?"},
+	{ID: "morning-first-thought", Category: "morning_pages", Text: "What is the first thing on your mind this morning? Follow it for a while."},
+
+	{ID: "theme-change", Category: "theme", Text: "You have been writing about {theme}. What has changed since you first wrote about it?"},
+	{ID: "theme-feeling", Category: "theme", Text: "How do you feel about {theme} right now? Start with one sentence, then go deeper."},
+	{ID: "theme-next-step", Category: "theme", Text: "What would a small step forward with {theme} look like this week?"},
+}
+
+// builtinTemplates are the entry templates every user has.
+var builtinTemplates = []models.EntryTemplate{
+	{
+		ID:          "daily-check-in",
+		Name:        "Daily check-in",
+		Description: "A quick look at how the day went.",
+		Fields: []models.TemplateField{
+			{Key: "mood", Label: "How are you feeling?", Type: "scale", Min: 1, Max: 5, Required: true, Hint: "1 = very low, 5 = very good"},
+			{Key: "highlight", Label: "Highlight of the day", Type: "text"},
+			{Key: "challenge", Label: "What was hard?", Type: "text"},
+			{Key: "intention", Label: "One intention for tomorrow", Type: "text"},
+		},
+	},
+	{
+		ID:          "gratitude-list",
+		Name:        "Gratitude list",
+		Description: "Three things you are grateful for and why they matter.",
+		Fields: []models.TemplateField{
+			{Key: "first", Label: "I'm grateful for", Type: "text", Required: true},
+			{Key: "second", Label: "I'm also grateful for", Type: "text"},
+			{Key: "third", Label: "And for", Type: "text"},
+			{Key: "why", Label: "Why these matter to me", Type: "long_text"},
+		},
+	},
+	{
+		ID:          "thought-record",
+		Name:        "Thought record",
+		Description: "A CBT thought record: catch an automatic thought, weigh the evidence and find a more balanced view.",
+		Fields: []models.TemplateField{
+			{Key: "situation", Label: "Situation", Type: "long_text", Required: true, Hint: "Where were you, what happened, who was there?"},
+			{Key: "emotions", Label: "Emotions", Type: "text", Required: true, Hint: "For example anxious, ashamed, angry"},
+			{Key: "intensity_before", Label: "How strong were they?", Type: "scale", Min: 0, Max: 100},
+			{Key: "automatic_thought", Label: "Automatic thought", Type: "long_text", Required: true, Hint: "What went through your mind?"},
+			{Key: "evidence_for", Label: "Evidence for the thought", Type: "long_text"},
+			{Key: "evidence_against", Label: "Evidence against the thought", Type: "long_text"},
+			{Key: "balanced_thought", Label: "Balanced thought", Type: "long_text", Required: true, Hint: "A fairer way to see the situation"},
+			{Key: "intensity_after", Label: "How strong are the emotions now?", Type: "scale", Min: 0, Max: 100},
+		},
+	},
+	{
+		ID:          "morning-pages",
+		Name:        "Morning pages",
+		Description: "Three pages of unfiltered writing first thing in the morning.",
+		Fields: []models.TemplateField{
+			{Key: "pages", Label: "Morning pages", Type: "long_text", Required: true, Hint: "About 750 words. Keep writing, don't edit."},
+		},
+	},
+}
+
+// libraryPrompt returns the built-in prompt with the given ID.
+func libraryPrompt(id string) (models.Prompt, bool) {
+	for _, prompt := range promptLibrary {
+		if prompt.ID == id {
+			return prompt, true
+		}
+	}
+	return models.Prompt{}, false
+}
+
+// builtinTemplate returns a copy of the built-in template with the given ID.
+func builtinTemplate(id string) (*models.EntryTemplate, bool) {
+	for _, template := range builtinTemplates {
+		if template.ID == id {
+			template.BuiltIn = true
+			return &template, true
+		}
+	}
+	return nil, false
+}
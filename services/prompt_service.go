@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"soulprint-backend/models"
+)
+
+const (
+	// promptMoodWindow is how far back entries count towards a suggestion's mood and
+	// themes.
+	promptMoodWindow = 7 * 24 * time.Hour
+	// promptRepeatWindow keeps prompts answered this recently from being suggested again.
+	promptRepeatWindow = 14 * 24 * time.Hour
+)
+
+// moodValences scores common mood labels from -1 (low) to 1 (good). Other moods are
+// scored with the sentiment lexicon.
+var moodValences = map[string]float64{
+	"positive": 1, "happy": 1, "great": 1, "grateful": 1, "excited": 1, "proud": 1, "joyful": 1,
+	"good": 0.5, "calm": 0.5, "content": 0.5, "hopeful": 0.5, "relaxed": 0.5,
+	"neutral": 0, "okay": 0, "ok": 0, "meh": 0,
+	"tired": -0.5, "down": -0.5, "low": -0.5, "bad": -0.5,
+	"negative": -1, "sad": -1, "anxious": -1, "stressed": -1, "angry": -1, "frustrated": -1,
+	"worried": -1, "lonely": -1, "overwhelmed": -1, "depressed": -1,
+}
+
+// PromptService serves the prompt library and suggests what to write about next.
+type PromptService struct {
+	journalService *JournalService
+	topicService   *TopicService
+	templates      *TemplateService
+}
+
+func NewPromptService(journalService *JournalService, topicService *TopicService, templates *TemplateService) *PromptService {
+	return &PromptService{
+		journalService: journalService,
+		topicService:   topicService,
+		templates:      templates,
+	}
+}
+
+// GetPrompts lists the prompt library, or one category of it.
+func (ps *PromptService) GetPrompts(category string) ([]models.Prompt, error) {
+	if category == "" {
+		return promptLibrary, nil
+	}
+	if !containsString(PromptCategories, category) {
+		return nil, fmt.Errorf("invalid category %q", category)
+	}
+	var prompts []models.Prompt
+	for _, prompt := range promptLibrary {
+		if prompt.Category == category {
+			prompts = append(prompts, prompt)
+		}
+	}
+	return prompts, nil
+}
+
+// SuggestPrompt picks the prompt for a user's next entry. Recent entries that lean
+// low get a CBT or gratitude prompt, a morning without an entry gets morning pages,
+// and a theme running through the week gets a prompt about it; otherwise it is a
+// daily question. Prompts answered in the last two weeks are skipped. The pick is
+// stable for the day. now should be in the user's timezone; category, if set, limits
+// the suggestion to one category.
+func (ps *PromptService) SuggestPrompt(userID, category string, now time.Time) (*models.PromptSuggestion, error) {
+	if category != "" && !containsString(PromptCategories, category) {
+		return nil, fmt.Errorf("invalid category %q", category)
+	}
+
+	entries, err := ps.journalService.GetEntriesInRange(userID, now.Add(-promptRepeatWindow), now.Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	answered := make(map[string]bool)
+	var recent []models.JournalEntry
+	for _, entry := range entries {
+		if entry.PromptID != "" {
+			answered[entry.PromptID] = true
+		}
+		if now.Sub(entry.CreatedAt) <= promptMoodWindow {
+			recent = append(recent, entry)
+		}
+	}
+
+	theme, err := ps.recentTheme(userID, recent)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &models.PromptSuggestion{}
+	if category == "" {
+		category, suggestion.Reason = suggestionCategory(recent, theme, now)
+	} else {
+		suggestion.Reason = fmt.Sprintf("Picked from the %s prompts.", strings.ReplaceAll(category, "_", " "))
+	}
+	if category == "theme" {
+		if theme == "" {
+			return nil, fmt.Errorf("no recent theme found")
+		}
+		suggestion.Theme = theme
+	}
+
+	suggestion.Prompt = pickPrompt(userID, category, answered, now)
+	suggestion.Prompt.Text = strings.ReplaceAll(suggestion.Prompt.Text, "{theme}", theme)
+	if suggestion.Prompt.Template != "" {
+		template, err := ps.templates.GetTemplate(userID, suggestion.Prompt.Template)
+		if err != nil {
+			return nil, err
+		}
+		suggestion.Template = template
+	}
+	return suggestion, nil
+}
+
+// suggestionCategory decides which kind of prompt fits the user's recent entries and
+// explains why.
+func suggestionCategory(recent []models.JournalEntry, theme string, now time.Time) (category, reason string) {
+	total, scored := 0.0, 0
+	wroteToday := false
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, entry := range recent {
+		if valence, ok := entryValence(entry); ok {
+			total += valence
+			scored++
+		}
+		if !entry.CreatedAt.Before(dayStart) {
+			wroteToday = true
+		}
+	}
+	average := 0.0
+	if scored > 0 {
+		average = total / float64(scored)
+	}
+
+	switch {
+	case scored >= 2 && average <= -0.5:
+		return "cbt", "Your recent entries have felt heavy. A thought record can help untangle what is weighing on you."
+	case scored > 0 && average < 0:
+		return "gratitude", "Your last few entries lean low. Noticing what went well can balance the picture."
+	case now.Hour() >= 5 && now.Hour() < 11 && !wroteToday:
+		return "morning_pages", "It's morning and you haven't written yet today. Morning pages help clear your head for the day."
+	case theme != "":
+		return "theme", fmt.Sprintf("You have written about %q several times this week.", theme)
+	case len(recent) == 0:
+		return "daily", "You haven't written this week. A short question is an easy way back in."
+	}
+	return "daily", "A question for today."
+}
+
+// entryValence scores an entry's mood from -1 to 1: the mood scale of a daily
+// check-in, the entry's mood label, or failing those the sentiment of its content.
+func entryValence(entry models.JournalEntry) (float64, bool) {
+	if entry.TemplateID == "daily-check-in" {
+		for _, answer := range entry.Answers {
+			if n, err := strconv.Atoi(answer.Value); answer.Key == "mood" && err == nil {
+				return float64(n-3) / 2, true
+			}
+		}
+	}
+	if mood := strings.ToLower(strings.TrimSpace(entry.Mood)); mood != "" {
+		if valence, ok := moodValences[mood]; ok {
+			return valence, true
+		}
+		if v := sentimentValence(sentiment(mood, entry.Language)); v != 0 {
+			return v, true
+		}
+	}
+	if entry.Envelope != nil {
+		return 0, false
+	}
+	return sentimentValence(sentiment(entry.Title+"\n"+entry.Content, entry.Language)), true
+}
+
+func sentimentValence(sentiment string) float64 {
+	switch sentiment {
+	case "positive":
+		return 1
+	case "negative":
+		return -1
+	}
+	return 0
+}
+
+// recentTheme returns the label of the largest topic that at least two recent entries
+// belong to, or else a keyword that at least two recent entries share.
+func (ps *PromptService) recentTheme(userID string, recent []models.JournalEntry) (string, error) {
+	if len(recent) < 2 {
+		return "", nil
+	}
+	recentIDs := make(map[string]bool, len(recent))
+	for _, entry := range recent {
+		recentIDs[entry.ID.Hex()] = true
+	}
+
+	topics, err := ps.topicService.GetTopics(userID, false)
+	if err != nil {
+		return "", err
+	}
+	for _, topic := range topics {
+		members := 0
+		for _, id := range topic.EntryIDs {
+			if recentIDs[id.Hex()] {
+				members++
+			}
+		}
+		if members >= 2 {
+			return topic.Label, nil
+		}
+	}
+
+	counts := make(map[string]int)
+	scores := make(map[string]float64)
+	for _, entry := range recent {
+		for _, keyword := range entry.Keywords {
+			counts[keyword.Term]++
+			scores[keyword.Term] += keyword.Score
+		}
+	}
+	var terms []string
+	for term, count := range counts {
+		if count >= 2 {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return "", nil
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := terms[i], terms[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return a < b
+	})
+	return terms[0], nil
+}
+
+// pickPrompt chooses a prompt of the category the user has not answered recently. The
+// choice rotates daily and differs between users.
+func pickPrompt(userID, category string, answered map[string]bool, now time.Time) models.Prompt {
+	var fresh, all []models.Prompt
+	for _, prompt := range promptLibrary {
+		if prompt.Category != category {
+			continue
+		}
+		all = append(all, prompt)
+		if !answered[prompt.ID] {
+			fresh = append(fresh, prompt)
+		}
+	}
+	if len(fresh) == 0 {
+		fresh = all
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	day := now.Year()*366 + now.YearDay()
+	return fresh[(int(h.Sum32()%1000)+day)%len(fresh)]
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxBatchEntries bounds the number of entries in one batch.
+const maxBatchEntries = 1000
+
+// batchStaleAfter is how long a running batch may go without a heartbeat before
+// another server picks it up. Heartbeats are sent every minute while it runs.
+const batchStaleAfter = 10 * time.Minute
+
+type ReflectionBatchService struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	aiService  *AIService
+	// limiter hands out one model call at a time to all batches in this process.
+	limiter <-chan time.Time
+}
+
+func NewReflectionBatchService(client *mongo.Client, aiService *AIService) *ReflectionBatchService {
+	rate := config.AppConfig.ReflectBatchRate
+	if rate <= 0 {
+		rate = 30
+	}
+	return &ReflectionBatchService{
+		client:     client,
+		collection: client.Database(config.AppConfig.MongoDatabase).Collection("reflection_batches"),
+		aiService:  aiService,
+		limiter:    time.NewTicker(time.Minute / time.Duration(rate)).C,
+	}
+}
+
+// EnsureIndexes creates the indexes used to list batches and find stale ones.
+func (bs *ReflectionBatchService) EnsureIndexes(ctx context.Context) error {
+	_, err := bs.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "heartbeat_at", Value: 1}}},
+	})
+	return err
+}
+
+// CreateBatch records a batch for the selected entries and starts generating their
+// reflections in the background. Progress is read with GetBatch.
+func (bs *ReflectionBatchService) CreateBatch(userID string, req models.BatchReflectionRequest) (*models.ReflectionBatch, error) {
+	entryIDs, err := bs.selectEntries(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(entryIDs) == 0 {
+		return nil, fmt.Errorf("invalid request: no entries match")
+	}
+	if len(entryIDs) > maxBatchEntries {
+		return nil, fmt.Errorf("invalid request: a batch can hold at most %d entries, %d selected", maxBatchEntries, len(entryIDs))
+	}
+	if err := bs.aiService.usage.CheckBudget(userID); err != nil {
+		return nil, err
+	}
+
+	reflectionType := req.Type
+	if reflectionType == "" {
+		reflectionType = "insight"
+	}
+	now := time.Now()
+	batch := &models.ReflectionBatch{
+		UserID:      userID,
+		Type:        reflectionType,
+		Force:       req.Force,
+		Status:      "running",
+		Total:       len(entryIDs),
+		Items:       make([]models.BatchItem, len(entryIDs)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		HeartbeatAt: now,
+	}
+	for i, entryID := range entryIDs {
+		batch.Items[i] = models.BatchItem{EntryID: entryID, Status: "pending"}
+	}
+
+	result, err := bs.collection.InsertOne(context.Background(), batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	batch.ID = result.InsertedID.(primitive.ObjectID)
+
+	go bs.run(batch)
+	return batch, nil
+}
+
+// selectEntries resolves a request to entry IDs, in the order given or oldest first.
+func (bs *ReflectionBatchService) selectEntries(userID string, req models.BatchReflectionRequest) ([]primitive.ObjectID, error) {
+	if len(req.EntryIDs) > 0 && req.Filter != nil {
+		return nil, fmt.Errorf("invalid request: send entry_ids or filter, not both")
+	}
+
+	if len(req.EntryIDs) > 0 {
+		seen := make(map[primitive.ObjectID]bool, len(req.EntryIDs))
+		entryIDs := make([]primitive.ObjectID, 0, len(req.EntryIDs))
+		for _, id := range req.EntryIDs {
+			objectID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid entry ID %q", id)
+			}
+			if !seen[objectID] {
+				seen[objectID] = true
+				entryIDs = append(entryIDs, objectID)
+			}
+		}
+		return entryIDs, nil
+	}
+
+	if req.Filter == nil {
+		return nil, fmt.Errorf("invalid request: entry_ids or filter is required")
+	}
+
+	// End-to-end encrypted entries need a decrypted copy from the client, which a
+	// batch cannot provide, so filters never select them.
+	filter := bson.M{"user_id": userID, "deleted_at": nil, "envelope": nil}
+	created := bson.M{}
+	if req.Filter.From != "" {
+		from, err := time.Parse("2006-01-02", req.Filter.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		created["$gte"] = from
+	}
+	if req.Filter.To != "" {
+		to, err := time.Parse("2006-01-02", req.Filter.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		created["$lt"] = to.AddDate(0, 0, 1)
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	if req.Filter.Tag != "" {
+		filter["tags"] = req.Filter.Tag
+	}
+	if req.Filter.WithoutReflections {
+		reflected, err := bs.aiService.collection.Distinct(context.Background(), "entry_id",
+			bson.M{"user_id": userID, "deleted_at": nil, "entry_id": bson.M{"$exists": true}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find reflected entries: %w", err)
+		}
+		filter["_id"] = bson.M{"$nin": reflected}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(maxBatchEntries + 1)
+	cursor, err := bs.aiService.journalService.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find journal entries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(context.Background(), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode journal entries: %w", err)
+	}
+	entryIDs := make([]primitive.ObjectID, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.ID
+	}
+	return entryIDs, nil
+}
+
+func (bs *ReflectionBatchService) GetBatch(userID, batchID string) (*models.ReflectionBatch, error) {
+	objectID, err := primitive.ObjectIDFromHex(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch ID: %w", err)
+	}
+
+	var batch models.ReflectionBatch
+	err = bs.collection.FindOne(context.Background(), bson.M{"_id": objectID, "user_id": userID}).Decode(&batch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("batch not found")
+		}
+		return nil, fmt.Errorf("failed to find batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// ResumeStaleBatches picks up running batches whose server stopped sending
+// heartbeats, e.g. because it was restarted. Entries that were in flight are
+// generated again; the reflection cache keeps that from costing a second call.
+func (bs *ReflectionBatchService) ResumeStaleBatches(ctx context.Context) (int, error) {
+	cursor, err := bs.collection.Find(ctx, bson.M{
+		"status":       "running",
+		"heartbeat_at": bson.M{"$lt": time.Now().Add(-batchStaleAfter)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find stale batches: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var batches []models.ReflectionBatch
+	if err = cursor.All(ctx, &batches); err != nil {
+		return 0, fmt.Errorf("failed to decode stale batches: %w", err)
+	}
+
+	resumed := 0
+	for i := range batches {
+		batch := batches[i]
+		// Claim the batch; another replica running this job may have got there first.
+		result, err := bs.collection.UpdateOne(ctx,
+			bson.M{"_id": batch.ID, "heartbeat_at": batch.HeartbeatAt},
+			bson.M{"$set": bson.M{"heartbeat_at": time.Now()}},
+		)
+		if err != nil {
+			return resumed, fmt.Errorf("failed to claim batch: %w", err)
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		go bs.run(&batch)
+		resumed++
+	}
+	return resumed, nil
+}
+
+// run generates the pending items of a batch with bounded concurrency. Once the
+// user's token budget runs out, the remaining items fail without calling the model.
+func (bs *ReflectionBatchService) run(batch *models.ReflectionBatch) {
+	done := make(chan struct{})
+	defer close(done)
+	go bs.heartbeat(batch.ID, done)
+
+	concurrency := config.AppConfig.ReflectBatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var budgetErr error
+
+	for i, item := range batch.Items {
+		if item.Status != "pending" {
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(index int, entryID primitive.ObjectID) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			mu.Lock()
+			err := budgetErr
+			mu.Unlock()
+			var reflection *models.Reflection
+			if err == nil {
+				reflection, err = bs.aiService.generateReflection(batch.UserID, models.ReflectionRequest{
+					EntryID: entryID.Hex(),
+					Type:    batch.Type,
+					Force:   batch.Force,
+				}, generateOptions{beforeCall: func() { <-bs.limiter }})
+			}
+
+			var exceeded *BudgetExceededError
+			if errors.As(err, &exceeded) {
+				mu.Lock()
+				budgetErr = err
+				mu.Unlock()
+			}
+			bs.finishItem(batch.ID, index, reflection, err)
+		}(i, item.EntryID)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	_, err := bs.collection.UpdateOne(context.Background(),
+		bson.M{"_id": batch.ID},
+		bson.M{"$set": bson.M{"status": "completed", "completed_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		log.Printf("batch %s: failed to mark completed: %v", batch.ID.Hex(), err)
+	}
+}
+
+func (bs *ReflectionBatchService) finishItem(batchID primitive.ObjectID, index int, reflection *models.Reflection, err error) {
+	prefix := fmt.Sprintf("items.%d.", index)
+	set := bson.M{"updated_at": time.Now()}
+	inc := bson.M{}
+	if err != nil {
+		set[prefix+"status"] = "failed"
+		set[prefix+"error"] = err.Error()
+		inc["failed"] = 1
+	} else {
+		set[prefix+"status"] = "succeeded"
+		if !reflection.ID.IsZero() {
+			set[prefix+"reflection_id"] = reflection.ID
+		}
+		inc["succeeded"] = 1
+		if reflection.Cached {
+			inc["cached"] = 1
+		}
+	}
+
+	// Only count an item once, even if a resumed run finishes it again.
+	_, updateErr := bs.collection.UpdateOne(context.Background(),
+		bson.M{"_id": batchID, prefix + "status": "pending"},
+		bson.M{"$set": set, "$inc": inc},
+	)
+	if updateErr != nil {
+		log.Printf("batch %s: failed to record item %d: %v", batchID.Hex(), index, updateErr)
+	}
+}
+
+func (bs *ReflectionBatchService) heartbeat(batchID primitive.ObjectID, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			_, err := bs.collection.UpdateOne(context.Background(),
+				bson.M{"_id": batchID},
+				bson.M{"$set": bson.M{"heartbeat_at": time.Now()}},
+			)
+			if err != nil {
+				log.Printf("batch %s: heartbeat failed: %v", batchID.Hex(), err)
+			}
+		}
+	}
+}
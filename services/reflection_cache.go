@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"soulprint-backend/models"
+	"soulprint-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reflectionCacheKey hashes everything a reflection is generated from. The entry ID
+// is part of the hash so identical text in two entries does not share a key.
+func reflectionCacheKey(entry *models.JournalEntry, reflectionType, tone, length, lang, model string) string {
+	h := sha256.New()
+	for _, part := range []string{entry.ID.Hex(), entry.Title, entry.Content, reflectionType, tone, length, lang, model, utils.PromptVersion} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedReflection returns the latest reflection generated from the same inputs, or
+// nil if there is none.
+func (ais *AIService) cachedReflection(userID string, entryID primitive.ObjectID, cacheKey string) (*models.Reflection, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"entry_id":   entryID,
+		"cache_key":  cacheKey,
+		"deleted_at": nil,
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var reflection models.Reflection
+	err := ais.collection.FindOne(context.Background(), filter, opts).Decode(&reflection)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cached reflection: %w", err)
+	}
+	if err := openReflection(ais.encryptor, &reflection); err != nil {
+		return nil, err
+	}
+	reflection.Cached = true
+	return &reflection, nil
+}
+
+// invalidateReflectionCache stops an entry's reflections from being served from the
+// cache. The cache key already covers the entry text; clearing it as well means an
+// edit that is later reverted still gets a fresh reflection.
+func (js *JournalService) invalidateReflectionCache(entryID primitive.ObjectID) error {
+	_, err := js.reflections.UpdateMany(context.Background(),
+		bson.M{"entry_id": entryID, "cache_key": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"cache_key": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate cached reflections: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxFeedbackComment bounds a feedback comment, in characters.
+const maxFeedbackComment = 2000
+
+// FeedbackGroups are the reflection fields feedback statistics can be grouped by.
+var FeedbackGroups = map[string]bool{
+	"type":           true,
+	"prompt_version": true,
+	"model":          true,
+	"tone":           true,
+	"length":         true,
+}
+
+func (ais *AIService) GetReflection(userID, reflectionID string) (*models.Reflection, error) {
+	objectID, err := primitive.ObjectIDFromHex(reflectionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reflection ID: %w", err)
+	}
+
+	var reflection models.Reflection
+	err = ais.collection.FindOne(context.Background(), bson.M{"_id": objectID, "user_id": userID, "deleted_at": nil}).Decode(&reflection)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("reflection not found")
+		}
+		return nil, fmt.Errorf("failed to find reflection: %w", err)
+	}
+	if err := openReflection(ais.encryptor, &reflection); err != nil {
+		return nil, err
+	}
+	return &reflection, nil
+}
+
+// RegenerateReflection writes a new version of a reflection, optionally in another
+// tone or length. The new version links back to the one it replaces and to the first
+// version; earlier versions are kept.
+func (ais *AIService) RegenerateReflection(userID, reflectionID string, req models.RegenerateRequest) (*models.Reflection, error) {
+	parent, err := ais.GetReflection(userID, reflectionID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.EntryID.IsZero() {
+		return nil, fmt.Errorf("invalid request: only entry reflections can be regenerated")
+	}
+
+	reflectionType := parent.Type
+	if reflectionType == "support" {
+		// The safety check runs again and decides whether support is still right.
+		reflectionType = "insight"
+	}
+	tone, length := req.Tone, req.Length
+	if tone == "" {
+		tone = parent.Tone
+	}
+	if length == "" {
+		length = parent.Length
+	}
+
+	return ais.generateReflection(userID, models.ReflectionRequest{
+		EntryID: parent.EntryID.Hex(),
+		Type:    reflectionType,
+		Force:   true,
+		Tone:    tone,
+		Length:  length,
+	}, generateOptions{parent: parent})
+}
+
+// GetReflectionVersions returns every version of a reflection, oldest first.
+func (ais *AIService) GetReflectionVersions(userID, reflectionID string) ([]models.Reflection, error) {
+	reflection, err := ais.GetReflection(userID, reflectionID)
+	if err != nil {
+		return nil, err
+	}
+	root := reflection.ID
+	if reflection.RootID != nil {
+		root = *reflection.RootID
+	}
+
+	filter := bson.M{
+		"user_id":    userID,
+		"deleted_at": nil,
+		"$or":        bson.A{bson.M{"_id": root}, bson.M{"root_id": root}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := ais.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reflection versions: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var versions []models.Reflection
+	if err = cursor.All(context.Background(), &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode reflection versions: %w", err)
+	}
+	if err := openReflections(ais.encryptor, versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// SubmitFeedback stores the user's rating of a reflection, replacing any earlier one.
+func (ais *AIService) SubmitFeedback(userID, reflectionID string, req models.FeedbackRequest) (*models.ReflectionFeedback, error) {
+	req.Comment = strings.TrimSpace(req.Comment)
+	if req.Thumbs != "" && req.Thumbs != "up" && req.Thumbs != "down" {
+		return nil, fmt.Errorf("invalid feedback: thumbs must be \"up\" or \"down\"")
+	}
+	if req.Rating != 0 && (req.Rating < 1 || req.Rating > 5) {
+		return nil, fmt.Errorf("invalid feedback: rating must be between 1 and 5")
+	}
+	if utf8.RuneCountInString(req.Comment) > maxFeedbackComment {
+		return nil, fmt.Errorf("invalid feedback: comment is longer than %d characters", maxFeedbackComment)
+	}
+	if req.Thumbs == "" && req.Rating == 0 && req.Comment == "" {
+		return nil, fmt.Errorf("invalid feedback: thumbs, rating or comment is required")
+	}
+
+	reflection, err := ais.GetReflection(userID, reflectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := req.Comment
+	if err := sealFields(ais.encryptor, userID, &comment); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"entry_id":       reflection.EntryID,
+		"thumbs":         req.Thumbs,
+		"rating":         req.Rating,
+		"comment":        comment,
+		"type":           reflection.Type,
+		"prompt_version": reflection.PromptVersion,
+		"model":          reflection.Model,
+		"tone":           reflection.Tone,
+		"length":         reflection.Length,
+		"updated_at":     now,
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var feedback models.ReflectionFeedback
+	err = ais.feedback.FindOneAndUpdate(context.Background(),
+		bson.M{"user_id": userID, "reflection_id": reflection.ID},
+		bson.M{"$set": set, "$setOnInsert": bson.M{"created_at": now}},
+		opts,
+	).Decode(&feedback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feedback: %w", err)
+	}
+	feedback.Comment = req.Comment
+	return &feedback, nil
+}
+
+func (ais *AIService) GetFeedback(userID, reflectionID string) (*models.ReflectionFeedback, error) {
+	objectID, err := primitive.ObjectIDFromHex(reflectionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reflection ID: %w", err)
+	}
+
+	var feedback models.ReflectionFeedback
+	err = ais.feedback.FindOne(context.Background(), bson.M{"user_id": userID, "reflection_id": objectID}).Decode(&feedback)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("feedback not found")
+		}
+		return nil, fmt.Errorf("failed to find feedback: %w", err)
+	}
+	if err := openFields(ais.encryptor, userID, &feedback.Comment); err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// GetFeedbackStats aggregates feedback across all users, grouped by the given
+// reflection fields (see FeedbackGroups), largest groups first.
+func (ais *AIService) GetFeedbackStats(groupBy []string) ([]models.FeedbackStats, error) {
+	key := bson.M{}
+	for _, field := range groupBy {
+		if !FeedbackGroups[field] {
+			return nil, fmt.Errorf("invalid group %q", field)
+		}
+		key[field] = "$" + field
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":         key,
+			"count":       bson.M{"$sum": 1},
+			"thumbs_up":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$thumbs", "up"}}, 1, 0}}},
+			"thumbs_down": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$thumbs", "down"}}, 1, 0}}},
+			"ratings":     bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$rating", 0}}, 1, 0}}},
+			"rating_sum":  bson.M{"$sum": "$rating"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+	}
+	cursor, err := ais.feedback.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var rows []struct {
+		Group      map[string]string `bson:"_id"`
+		Count      int               `bson:"count"`
+		ThumbsUp   int               `bson:"thumbs_up"`
+		ThumbsDown int               `bson:"thumbs_down"`
+		Ratings    int               `bson:"ratings"`
+		RatingSum  int               `bson:"rating_sum"`
+	}
+	if err = cursor.All(context.Background(), &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode feedback stats: %w", err)
+	}
+
+	stats := make([]models.FeedbackStats, len(rows))
+	for i, row := range rows {
+		stats[i] = models.FeedbackStats{
+			Group:      row.Group,
+			Count:      row.Count,
+			ThumbsUp:   row.ThumbsUp,
+			ThumbsDown: row.ThumbsDown,
+			Ratings:    row.Ratings,
+		}
+		if row.Ratings > 0 {
+			average := float64(row.RatingSum) / float64(row.Ratings)
+			stats[i].AverageRating = &average
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+	"soulprint-backend/notifications"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reminderGrace is how long after the scheduled time a missed reminder is still sent,
+// e.g. after a deploy. Later than that the reminder is skipped for the day.
+const reminderGrace = 3 * time.Hour
+
+type ReminderService struct {
+	client         *mongo.Client
+	collection     *mongo.Collection
+	journalService *JournalService
+	dispatcher     *notifications.Dispatcher
+}
+
+func NewReminderService(client *mongo.Client, journalService *JournalService, dispatcher *notifications.Dispatcher) *ReminderService {
+	collection := client.Database(config.AppConfig.MongoDatabase).Collection("reminder_schedules")
+	return &ReminderService{
+		client:         client,
+		collection:     collection,
+		journalService: journalService,
+		dispatcher:     dispatcher,
+	}
+}
+
+func (rs *ReminderService) CreateReminder(userID string, req models.ReminderRequest) (*models.ReminderSchedule, error) {
+	reminder := &models.ReminderSchedule{
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if err := rs.apply(reminder, req); err != nil {
+		return nil, err
+	}
+
+	result, err := rs.collection.InsertOne(context.Background(), reminder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+
+	reminder.ID = result.InsertedID.(primitive.ObjectID)
+	return reminder, nil
+}
+
+func (rs *ReminderService) GetReminders(userID string) ([]models.ReminderSchedule, error) {
+	cursor, err := rs.collection.Find(context.Background(), bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reminders: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var reminders []models.ReminderSchedule
+	if err = cursor.All(context.Background(), &reminders); err != nil {
+		return nil, fmt.Errorf("failed to decode reminders: %w", err)
+	}
+
+	return reminders, nil
+}
+
+func (rs *ReminderService) UpdateReminder(userID, reminderID string, req models.ReminderRequest) (*models.ReminderSchedule, error) {
+	reminder, err := rs.getReminder(userID, reminderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rs.apply(reminder, req); err != nil {
+		return nil, err
+	}
+
+	if _, err := rs.collection.ReplaceOne(context.Background(), bson.M{"_id": reminder.ID, "user_id": userID}, reminder); err != nil {
+		return nil, fmt.Errorf("failed to update reminder: %w", err)
+	}
+
+	return reminder, nil
+}
+
+func (rs *ReminderService) DeleteReminder(userID, reminderID string) error {
+	objectID, err := primitive.ObjectIDFromHex(reminderID)
+	if err != nil {
+		return fmt.Errorf("invalid reminder ID: %w", err)
+	}
+
+	result, err := rs.collection.DeleteOne(context.Background(), bson.M{"_id": objectID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+
+	return nil
+}
+
+// SendTestReminder delivers a reminder right away, ignoring the schedule.
+func (rs *ReminderService) SendTestReminder(userID, reminderID string) error {
+	reminder, err := rs.getReminder(userID, reminderID)
+	if err != nil {
+		return err
+	}
+	return rs.dispatcher.Send(context.Background(), reminder.Channel, reminderNotification(reminder))
+}
+
+// SendDueReminders delivers every reminder whose time has come in the user's timezone,
+// unless the user already wrote an entry that day. It returns how many were sent.
+func (rs *ReminderService) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	cursor, err := rs.collection.Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find reminders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var reminders []models.ReminderSchedule
+	if err = cursor.All(ctx, &reminders); err != nil {
+		return 0, fmt.Errorf("failed to decode reminders: %w", err)
+	}
+
+	sent := 0
+	for i := range reminders {
+		reminder := &reminders[i]
+		due, dayStart, err := reminderDue(reminder, now)
+		if err != nil {
+			log.Printf("reminders: skipping %s: %v", reminder.ID.Hex(), err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		journaled, err := rs.journalService.HasEntrySince(reminder.UserID, dayStart)
+		if err != nil {
+			return sent, err
+		}
+		if journaled {
+			continue
+		}
+
+		if err := rs.dispatcher.Send(ctx, reminder.Channel, reminderNotification(reminder)); err != nil {
+			log.Printf("reminders: failed to send %s via %s: %v", reminder.ID.Hex(), reminder.Channel, err)
+			continue
+		}
+
+		_, err = rs.collection.UpdateOne(ctx, bson.M{"_id": reminder.ID}, bson.M{"$set": bson.M{"last_sent_at": now}})
+		if err != nil {
+			return sent, fmt.Errorf("failed to mark reminder sent: %w", err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// reminderDue reports whether the reminder should fire at now, along with the start
+// of the user's local day.
+func reminderDue(reminder *models.ReminderSchedule, now time.Time) (bool, time.Time, error) {
+	loc, err := time.LoadLocation(reminder.Timezone)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid timezone %q", reminder.Timezone)
+	}
+	clock, err := time.Parse("15:04", reminder.TimeOfDay)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid time of day %q", reminder.TimeOfDay)
+	}
+
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	dueAt := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+
+	if len(reminder.Weekdays) > 0 && !containsInt(reminder.Weekdays, int(local.Weekday())) {
+		return false, dayStart, nil
+	}
+	if local.Before(dueAt) || local.Sub(dueAt) > reminderGrace {
+		return false, dayStart, nil
+	}
+	if reminder.LastSentAt != nil && !reminder.LastSentAt.Before(dayStart) {
+		return false, dayStart, nil
+	}
+	return true, dayStart, nil
+}
+
+func (rs *ReminderService) apply(reminder *models.ReminderSchedule, req models.ReminderRequest) error {
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", req.Timezone)
+	}
+	if _, err := time.Parse("15:04", req.TimeOfDay); err != nil {
+		return fmt.Errorf("invalid time of day %q, expected HH:MM", req.TimeOfDay)
+	}
+	for _, day := range req.Weekdays {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid weekday %d, expected 0 (Sunday) to 6 (Saturday)", day)
+		}
+	}
+	if !rs.dispatcher.Has(req.Channel) {
+		return fmt.Errorf("invalid channel %q, available: %v", req.Channel, rs.dispatcher.Channels())
+	}
+	if (req.Channel == "email" || req.Channel == "webhook") && req.Target == "" {
+		return fmt.Errorf("invalid target: %s reminders require a target", req.Channel)
+	}
+	if req.Channel == "webhook" {
+		if err := validateWebhookURL(req.Target); err != nil {
+			return err
+		}
+	}
+
+	reminder.Enabled = req.Enabled == nil || *req.Enabled
+	reminder.TimeOfDay = req.TimeOfDay
+	reminder.Weekdays = req.Weekdays
+	reminder.Timezone = req.Timezone
+	reminder.Channel = req.Channel
+	reminder.Target = req.Target
+	reminder.UpdatedAt = time.Now()
+	return nil
+}
+
+func (rs *ReminderService) getReminder(userID, reminderID string) (*models.ReminderSchedule, error) {
+	objectID, err := primitive.ObjectIDFromHex(reminderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder ID: %w", err)
+	}
+
+	var reminder models.ReminderSchedule
+	err = rs.collection.FindOne(context.Background(), bson.M{"_id": objectID, "user_id": userID}).Decode(&reminder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("reminder not found")
+		}
+		return nil, fmt.Errorf("failed to find reminder: %w", err)
+	}
+
+	return &reminder, nil
+}
+
+// EnsureIndexes creates the indexes used by reminder queries.
+func (rs *ReminderService) EnsureIndexes(ctx context.Context) error {
+	_, err := rs.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "enabled", Value: 1}}},
+	})
+	return err
+}
+
+func reminderNotification(reminder *models.ReminderSchedule) notifications.Notification {
+	return notifications.Notification{
+		UserID:  reminder.UserID,
+		To:      reminder.Target,
+		Kind:    "reminder",
+		Subject: "A moment for your journal",
+		Body:    "You haven't written in your journal today. Take a few minutes to capture how your day went.",
+		Data: map[string]interface{}{
+			"reminder_id": reminder.ID.Hex(),
+		},
+	}
+}
+
+func containsInt(values []int, target int) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+	"soulprint-backend/notifications"
+)
+
+func TestApplyRejectsInternalWebhookTargets(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	dispatcher := notifications.NewDispatcher()
+	dispatcher.Register(notifications.NewWebhookChannel(NewWebhookHTTPClient()))
+	rs := &ReminderService{dispatcher: dispatcher}
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"http://127.0.0.1:8080/remind", false},
+		{"http://localhost/remind", false},
+		{"http://[::1]/remind", false},
+		{"http://10.1.2.3/remind", false},
+		{"http://172.16.0.5/remind", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://100.100.100.200/", false},
+		{"file:///etc/passwd", false},
+		{"ftp://93.184.216.34/remind", false},
+		{"https://93.184.216.34/remind", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := models.ReminderRequest{TimeOfDay: "08:00", Channel: "webhook", Target: tt.target}
+			err := rs.apply(&models.ReminderSchedule{}, req)
+			if (err == nil) != tt.allowed {
+				t.Errorf("err = %v, want allowed = %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestReminderWebhookRefusesInternalAddresses(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer receiver.Close()
+
+	// A target stored before validation existed is still refused when sending.
+	channel := notifications.NewWebhookChannel(NewWebhookHTTPClient())
+	if err := channel.Send(context.Background(), notifications.Notification{To: receiver.URL}); err == nil || called {
+		t.Fatalf("reminder sent to a loopback receiver (err %v)", err)
+	}
+}
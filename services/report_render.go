@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"soulprint-backend/models"
+)
+
+var reportFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("January 2, 2006") },
+	"monthName": func(i int) string {
+		return time.Month(i + 1).String()
+	},
+	"bar": func(n int) string { return strings.Repeat("█", n) },
+	"paragraphs": func(text string) []string {
+		var paragraphs []string
+		for _, p := range strings.Split(text, "\n\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				paragraphs = append(paragraphs, p)
+			}
+		}
+		return paragraphs
+	},
+	"score": func(f float64) string {
+		switch {
+		case f > 0.2:
+			return "bright"
+		case f < -0.2:
+			return "heavy"
+		default:
+			return "steady"
+		}
+	},
+}
+
+const yearReviewMarkdown = `# {{.Year}} in Review
+
+{{.Stats.TotalEntries}} entries · {{.Stats.TotalWords}} words · {{.Stats.ActiveDays}} days written · longest streak {{.Stats.LongestStreak}} days
+
+## Your year, in your words
+
+{{.Narrative}}
+
+## Entries by month
+
+{{range $i, $n := .Stats.EntriesByMonth}}- {{monthName $i}}: {{$n}} {{bar $n}}
+{{end}}
+## Mood trend
+
+| Month | Entries | Top mood | Tone |
+|-------|---------|----------|------|
+{{range .Stats.MoodTrend}}{{if .Entries}}| {{.Month}} | {{.Entries}} | {{or .TopMood "-"}} | {{score .AverageScore}} |
+{{end}}{{end}}
+{{if .Stats.TopTags}}## Top tags
+
+{{range .Stats.TopTags}}- {{.Term}} ({{.Count}})
+{{end}}
+{{end}}{{if .Stats.TopThemes}}## Recurring themes
+
+{{range .Stats.TopThemes}}- {{.Term}} ({{.Count}})
+{{end}}
+{{end}}## Highlights
+
+{{range .Highlights}}### {{.Title}}
+*{{date .Date}} — {{.Reason}}*
+
+> {{.Excerpt}}
+
+{{end}}`
+
+const yearReviewHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Year}} in Review</title>
+<style>
+body { font-family: Georgia, serif; max-width: 720px; margin: 40px auto; color: #2d2a32; line-height: 1.6; }
+h1, h2, h3 { font-family: Helvetica, Arial, sans-serif; }
+.stats { color: #6b6375; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #e4e0ea; padding: 4px 8px; text-align: left; }
+.bar { background: #b39ddb; height: 10px; display: inline-block; }
+blockquote { border-left: 3px solid #b39ddb; margin: 0; padding-left: 12px; color: #4a4453; }
+</style>
+</head>
+<body>
+<h1>{{.Year}} in Review</h1>
+<p class="stats">{{.Stats.TotalEntries}} entries · {{.Stats.TotalWords}} words · {{.Stats.ActiveDays}} days written · longest streak {{.Stats.LongestStreak}} days</p>
+
+<h2>Your year, in your words</h2>
+{{range paragraphs .Narrative}}<p>{{.}}</p>
+{{end}}
+<h2>Entries by month</h2>
+<table>
+{{range $i, $n := .Stats.EntriesByMonth}}<tr><td>{{monthName $i}}</td><td>{{$n}}</td><td><span class="bar" style="width: {{$n}}0px"></span></td></tr>
+{{end}}</table>
+
+<h2>Mood trend</h2>
+<table>
+<tr><th>Month</th><th>Entries</th><th>Top mood</th><th>Tone</th></tr>
+{{range .Stats.MoodTrend}}{{if .Entries}}<tr><td>{{.Month}}</td><td>{{.Entries}}</td><td>{{or .TopMood "-"}}</td><td>{{score .AverageScore}}</td></tr>
+{{end}}{{end}}</table>
+{{if .Stats.TopTags}}
+<h2>Top tags</h2>
+<ul>
+{{range .Stats.TopTags}}<li>{{.Term}} ({{.Count}})</li>
+{{end}}</ul>
+{{end}}{{if .Stats.TopThemes}}
+<h2>Recurring themes</h2>
+<ul>
+{{range .Stats.TopThemes}}<li>{{.Term}} ({{.Count}})</li>
+{{end}}</ul>
+{{end}}
+<h2>Highlights</h2>
+{{range .Highlights}}<h3>{{.Title}}</h3>
+<p class="stats">{{date .Date}} — {{.Reason}}</p>
+<blockquote>{{.Excerpt}}</blockquote>
+{{end}}</body>
+</html>
+`
+
+var (
+	yearReviewMarkdownTmpl = template.Must(template.New("year_review.md").Funcs(reportFuncs).Parse(yearReviewMarkdown))
+	yearReviewHTMLTmpl     = htmltemplate.Must(htmltemplate.New("year_review.html").Funcs(reportFuncs).Parse(yearReviewHTML))
+)
+
+// RenderYearReviewMarkdown renders a year review as a Markdown document.
+func RenderYearReviewMarkdown(review *models.YearReview) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := yearReviewMarkdownTmpl.Execute(&buf, review); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderYearReviewHTML renders a year review as a standalone HTML page.
+func RenderYearReviewHTML(review *models.YearReview) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := yearReviewHTMLTmpl.Execute(&buf, review); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+	"soulprint-backend/safety"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReportService struct {
+	client         *mongo.Client
+	collection     *mongo.Collection
+	journalService *JournalService
+	aiService      *AIService
+	digestService  *DigestService
+}
+
+func NewReportService(client *mongo.Client, journalService *JournalService, aiService *AIService, digestService *DigestService) *ReportService {
+	collection := client.Database(config.AppConfig.MongoDatabase).Collection("year_reviews")
+	return &ReportService{
+		client:         client,
+		collection:     collection,
+		journalService: journalService,
+		aiService:      aiService,
+		digestService:  digestService,
+	}
+}
+
+// EnsureIndexes creates the unique index backing one report per user and year.
+func (rs *ReportService) EnsureIndexes(ctx context.Context) error {
+	_, err := rs.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "year", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// GetYearReview returns the stored report for the year, generating it first when it
+// does not exist yet or when refresh is set.
+func (rs *ReportService) GetYearReview(userID string, year int, refresh bool) (*models.YearReview, error) {
+	if !refresh {
+		var review models.YearReview
+		err := rs.collection.FindOne(context.Background(), bson.M{"user_id": userID, "year": year}).Decode(&review)
+		if err == nil {
+			if err := openYearReview(rs.aiService.encryptor, &review); err != nil {
+				return nil, err
+			}
+			return &review, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to find year review: %w", err)
+		}
+	}
+
+	return rs.GenerateYearReview(userID, year)
+}
+
+// GenerateYearReview builds the report from the year's entries and reflections and stores it.
+func (rs *ReportService) GenerateYearReview(userID string, year int) (*models.YearReview, error) {
+	if year < 1970 || year > time.Now().Year() {
+		return nil, fmt.Errorf("invalid year %d", year)
+	}
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	entries, err := rs.journalService.GetEntriesInRange(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no journal entries in %d", year)
+	}
+
+	reflections, err := rs.aiService.GetReflectionsInRange(userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// The narrative needs plaintext; end-to-end encrypted journals get the stats only.
+	e2e, err := rs.journalService.settings.E2EEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	narrative := ""
+	var flag *models.SafetyFlag
+	if readable := readableEntries(entries); !e2e && len(readable) > 0 {
+		// As with digests, entries at risk are left out of the narrative, which then
+		// leads with the supportive response.
+		assessment, unflagged, err := rs.digestService.screenEntries(userID, readable, "report")
+		if err != nil {
+			return nil, err
+		}
+		if len(unflagged) > 0 {
+			redactor, err := rs.aiService.redactorFor(userID)
+			if err != nil {
+				return nil, err
+			}
+			lang, err := rs.aiService.digestLanguage(userID, unflagged)
+			if err != nil {
+				return nil, err
+			}
+			narrative, err = rs.digestService.summarize(userID, unflagged, "yearly", lang, redactor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate narrative: %w", err)
+			}
+		}
+		if assessment.Flagged() {
+			flag = safetyFlag(assessment)
+			narrative = strings.TrimSpace(safety.SupportiveResponse(assessment, config.AppConfig.CrisisResources) + "\n\n" + narrative)
+		}
+	}
+
+	review := &models.YearReview{
+		UserID:      userID,
+		Year:        year,
+		Stats:       rs.buildStats(year, entries, reflections),
+		Highlights:  rs.pickHighlights(entries, reflections),
+		Narrative:   narrative,
+		Safety:      flag,
+		GeneratedAt: time.Now(),
+	}
+
+	stored, err := sealYearReview(rs.aiService.encryptor, review)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"user_id": userID, "year": year}
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	if err := rs.collection.FindOneAndReplace(context.Background(), filter, stored, opts).Decode(review); err != nil {
+		return nil, fmt.Errorf("failed to save year review: %w", err)
+	}
+	if err := openYearReview(rs.aiService.encryptor, review); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (rs *ReportService) buildStats(year int, entries []models.JournalEntry, reflections []models.Reflection) models.YearStats {
+	stats := models.YearStats{
+		TotalEntries:    len(entries),
+		EntriesByMonth:  make([]int, 12),
+		SentimentCounts: map[string]int{"positive": 0, "negative": 0, "neutral": 0},
+	}
+
+	days := make(map[string]bool)
+	tags := make(map[string]int)
+	months := make([]models.MonthMood, 12)
+	scores := make([]float64, 12)
+	for i := range months {
+		months[i].Month = fmt.Sprintf("%d-%02d", year, i+1)
+	}
+
+	for _, entry := range entries {
+		month := int(entry.CreatedAt.UTC().Month()) - 1
+		stats.EntriesByMonth[month]++
+		stats.TotalWords += len(strings.Fields(entry.Content))
+		days[entry.CreatedAt.UTC().Format("2006-01-02")] = true
+
+		for _, tag := range entry.Tags {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				tags[tag]++
+			}
+		}
+
+		months[month].Entries++
+		if entry.Mood != "" {
+			if months[month].Moods == nil {
+				months[month].Moods = make(map[string]int)
+			}
+			months[month].Moods[strings.ToLower(entry.Mood)]++
+		}
+		switch rs.aiService.extractSentiment(entry.Content, entry.Language) {
+		case "positive":
+			scores[month]++
+		case "negative":
+			scores[month]--
+		}
+	}
+
+	for i := range months {
+		if months[i].Entries > 0 {
+			months[i].AverageScore = scores[i] / float64(months[i].Entries)
+		}
+		if top := topTerms(months[i].Moods, 1); len(top) > 0 {
+			months[i].TopMood = top[0].Term
+		}
+	}
+	stats.MoodTrend = months
+
+	themes := make(map[string]int)
+	for _, reflection := range reflections {
+		for _, keyword := range reflection.Keywords {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				themes[keyword]++
+			}
+		}
+		if reflection.Sentiment != "" {
+			stats.SentimentCounts[reflection.Sentiment]++
+		}
+	}
+
+	stats.ActiveDays = len(days)
+	stats.LongestStreak = longestStreak(days)
+	stats.TopTags = topTerms(tags, 10)
+	stats.TopThemes = topTerms(themes, 10)
+
+	return stats
+}
+
+// pickHighlights selects a handful of entries worth revisiting: the first and last
+// entries of the year, the longest one, and the most recent entries whose
+// reflections came out positive.
+func (rs *ReportService) pickHighlights(entries []models.JournalEntry, reflections []models.Reflection) []models.ReportHighlight {
+	var highlights []models.ReportHighlight
+	seen := make(map[string]bool)
+	add := func(entry models.JournalEntry, reason string) {
+		if seen[entry.ID.Hex()] {
+			return
+		}
+		seen[entry.ID.Hex()] = true
+		highlights = append(highlights, models.ReportHighlight{
+			EntryID: entry.ID,
+			Title:   entry.Title,
+			Date:    entry.CreatedAt,
+			Reason:  reason,
+			Excerpt: excerpt(entry.Content, 240),
+		})
+	}
+
+	add(entries[0], "Your first entry of the year")
+
+	longest := entries[0]
+	for _, entry := range entries {
+		if len(entry.Content) > len(longest.Content) {
+			longest = entry
+		}
+	}
+	add(longest, "Your longest entry")
+
+	byID := make(map[string]models.JournalEntry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID.Hex()] = entry
+	}
+	positives := 0
+	for i := len(reflections) - 1; i >= 0 && positives < 3; i-- {
+		if reflections[i].Sentiment != "positive" {
+			continue
+		}
+		if entry, ok := byID[reflections[i].EntryID.Hex()]; ok && !seen[entry.ID.Hex()] {
+			add(entry, "A bright moment")
+			positives++
+		}
+	}
+
+	add(entries[len(entries)-1], "Your last entry of the year")
+
+	return highlights
+}
+
+func longestStreak(days map[string]bool) int {
+	longest := 0
+	for day := range days {
+		date, _ := time.Parse("2006-01-02", day)
+		if days[date.AddDate(0, 0, -1).Format("2006-01-02")] {
+			continue // not the start of a streak
+		}
+		length := 1
+		for days[date.AddDate(0, 0, length).Format("2006-01-02")] {
+			length++
+		}
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+func topTerms(counts map[string]int, limit int) []models.TermCount {
+	terms := make([]models.TermCount, 0, len(counts))
+	for term, count := range counts {
+		terms = append(terms, models.TermCount{Term: term, Count: count})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+	return terms
+}
+
+func excerpt(content string, limit int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= limit {
+		return content
+	}
+	return strings.TrimSpace(string(runes[:limit])) + "…"
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/encryption"
+	"soulprint-backend/language"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxKeyBackupSize bounds a decoded key backup blob. Backups hold wrapped keys, not data.
+const maxKeyBackupSize = 64 << 10
+
+type SettingsService struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	keyBackups *mongo.Collection
+	encryptor  *encryption.Encryptor
+}
+
+func NewSettingsService(client *mongo.Client, encryptor *encryption.Encryptor) *SettingsService {
+	db := client.Database(config.AppConfig.MongoDatabase)
+	return &SettingsService{
+		client:     client,
+		collection: db.Collection("user_settings"),
+		keyBackups: db.Collection("key_backups"),
+		encryptor:  encryptor,
+	}
+}
+
+// EnsureIndexes creates the unique indexes behind one settings document per user and
+// one backup per user and key.
+func (ss *SettingsService) EnsureIndexes(ctx context.Context) error {
+	if _, err := ss.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	_, err := ss.keyBackups.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "key_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// GetSettings returns the user's settings, or the defaults if none are stored.
+func (ss *SettingsService) GetSettings(userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := ss.collection.FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		settings = models.UserSettings{UserID: userID}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find settings: %w", err)
+	}
+	if err := ss.openSettings(&settings); err != nil {
+		return nil, err
+	}
+	applyDefaultSettings(&settings)
+	return &settings, nil
+}
+
+func (ss *SettingsService) UpdateSettings(userID string, req models.SettingsRequest) (*models.UserSettings, error) {
+	set := bson.M{"updated_at": time.Now()}
+	if req.E2EEnabled != nil {
+		set["e2e_enabled"] = *req.E2EEnabled
+	}
+	if req.RedactPII != nil {
+		set["redact_pii"] = *req.RedactPII
+	}
+	if req.SensitiveNames != nil {
+		names := make([]string, 0, len(*req.SensitiveNames))
+		for _, name := range *req.SensitiveNames {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		fields := make([]*string, len(names))
+		for i := range names {
+			fields[i] = &names[i]
+		}
+		if err := sealFields(ss.encryptor, userID, fields...); err != nil {
+			return nil, err
+		}
+		set["sensitive_names"] = names
+	}
+	if req.ReflectionLanguage != nil {
+		code := language.Normalize(*req.ReflectionLanguage)
+		if code != "" && !language.Supported(code) {
+			return nil, fmt.Errorf("invalid reflection_language %q", *req.ReflectionLanguage)
+		}
+		set["reflection_language"] = code
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var settings models.UserSettings
+	err := ss.collection.FindOneAndUpdate(context.Background(),
+		bson.M{"user_id": userID},
+		bson.M{"$set": set, "$setOnInsert": bson.M{"user_id": userID}},
+		opts,
+	).Decode(&settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	if err := ss.openSettings(&settings); err != nil {
+		return nil, err
+	}
+	applyDefaultSettings(&settings)
+	return &settings, nil
+}
+
+// openSettings decrypts the sensitive names, which are encrypted at rest.
+func (ss *SettingsService) openSettings(settings *models.UserSettings) error {
+	fields := make([]*string, len(settings.SensitiveNames))
+	for i := range settings.SensitiveNames {
+		fields[i] = &settings.SensitiveNames[i]
+	}
+	return openFields(ss.encryptor, settings.UserID, fields...)
+}
+
+// E2EEnabled reports whether the user's journal is in end-to-end encrypted mode.
+func (ss *SettingsService) E2EEnabled(userID string) (bool, error) {
+	settings, err := ss.GetSettings(userID)
+	if err != nil {
+		return false, err
+	}
+	return settings.E2EEnabled, nil
+}
+
+func applyDefaultSettings(settings *models.UserSettings) {
+	if settings.RedactPII == nil {
+		enabled := true
+		settings.RedactPII = &enabled
+	}
+}
+
+func (ss *SettingsService) GetKeyBackups(userID string) ([]models.KeyBackup, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := ss.keyBackups.Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find key backups: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var backups []models.KeyBackup
+	if err = cursor.All(context.Background(), &backups); err != nil {
+		return nil, fmt.Errorf("failed to decode key backups: %w", err)
+	}
+	return backups, nil
+}
+
+func (ss *SettingsService) GetKeyBackup(userID, keyID string) (*models.KeyBackup, error) {
+	var backup models.KeyBackup
+	err := ss.keyBackups.FindOne(context.Background(), bson.M{"user_id": userID, "key_id": keyID}).Decode(&backup)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("key backup not found")
+		}
+		return nil, fmt.Errorf("failed to find key backup: %w", err)
+	}
+	return &backup, nil
+}
+
+// PutKeyBackup stores or replaces the backup for a key. The blob is opaque to the
+// server; it is only checked to be base64 and of reasonable size.
+func (ss *SettingsService) PutKeyBackup(userID, keyID string, req models.KeyBackupRequest) (*models.KeyBackup, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("invalid key ID")
+	}
+	blob, err := base64.StdEncoding.DecodeString(req.Blob)
+	if err != nil || len(blob) == 0 {
+		return nil, fmt.Errorf("invalid blob: expected non-empty base64")
+	}
+	if len(blob) > maxKeyBackupSize {
+		return nil, fmt.Errorf("invalid blob: larger than %d bytes", maxKeyBackupSize)
+	}
+
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var backup models.KeyBackup
+	err = ss.keyBackups.FindOneAndUpdate(context.Background(),
+		bson.M{"user_id": userID, "key_id": keyID},
+		bson.M{
+			"$set":         bson.M{"blob": req.Blob, "metadata": req.Metadata, "updated_at": now},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		opts,
+	).Decode(&backup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store key backup: %w", err)
+	}
+	return &backup, nil
+}
+
+func (ss *SettingsService) DeleteKeyBackup(userID, keyID string) error {
+	result, err := ss.keyBackups.DeleteOne(context.Background(), bson.M{"user_id": userID, "key_id": keyID})
+	if err != nil {
+		return fmt.Errorf("failed to delete key backup: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("key backup not found")
+	}
+	return nil
+}
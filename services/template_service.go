@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxTemplateFields  = 30
+	maxTemplateNameLen = 100
+)
+
+var (
+	templateFieldKey   = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+	templateFieldTypes = map[string]bool{"text": true, "long_text": true, "scale": true, "choice": true}
+)
+
+// TemplateService manages entry templates: the built-in ones and those users define.
+type TemplateService struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateService(client *mongo.Client) *TemplateService {
+	return &TemplateService{
+		collection: client.Database(config.AppConfig.MongoDatabase).Collection("entry_templates"),
+	}
+}
+
+// EnsureIndexes creates the indexes used by template queries.
+func (ts *TemplateService) EnsureIndexes(ctx context.Context) error {
+	_, err := ts.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	return err
+}
+
+// GetTemplates lists the built-in templates followed by the user's own, oldest first.
+func (ts *TemplateService) GetTemplates(userID string) ([]models.EntryTemplate, error) {
+	templates := make([]models.EntryTemplate, 0, len(builtinTemplates))
+	for _, template := range builtinTemplates {
+		template.BuiltIn = true
+		templates = append(templates, template)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := ts.collection.Find(context.Background(), bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find templates: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var own []models.EntryTemplate
+	if err = cursor.All(context.Background(), &own); err != nil {
+		return nil, fmt.Errorf("failed to decode templates: %w", err)
+	}
+	return append(templates, own...), nil
+}
+
+// GetTemplate returns a built-in template or one of the user's own.
+func (ts *TemplateService) GetTemplate(userID, templateID string) (*models.EntryTemplate, error) {
+	if template, ok := builtinTemplate(templateID); ok {
+		return template, nil
+	}
+
+	var template models.EntryTemplate
+	err := ts.collection.FindOne(context.Background(), bson.M{"_id": templateID, "user_id": userID}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, fmt.Errorf("failed to find template: %w", err)
+	}
+	return &template, nil
+}
+
+func (ts *TemplateService) CreateTemplate(userID string, req models.TemplateRequest) (*models.EntryTemplate, error) {
+	fields, err := validateTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &models.EntryTemplate{
+		ID:          primitive.NewObjectID().Hex(),
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Fields:      fields,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := ts.collection.InsertOne(context.Background(), template); err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return template, nil
+}
+
+// UpdateTemplate replaces a user template. Entries already written with it keep their
+// answers as they were.
+func (ts *TemplateService) UpdateTemplate(userID, templateID string, req models.TemplateRequest) (*models.EntryTemplate, error) {
+	if _, ok := builtinTemplate(templateID); ok {
+		return nil, fmt.Errorf("invalid template: built-in templates cannot be changed")
+	}
+	fields, err := validateTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":        strings.TrimSpace(req.Name),
+		"description": strings.TrimSpace(req.Description),
+		"fields":      fields,
+		"updated_at":  time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var template models.EntryTemplate
+	err = ts.collection.FindOneAndUpdate(context.Background(), bson.M{"_id": templateID, "user_id": userID}, update, opts).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return &template, nil
+}
+
+// DeleteTemplate deletes a user template. Entries written with it keep their
+// template_id and answers.
+func (ts *TemplateService) DeleteTemplate(userID, templateID string) error {
+	if _, ok := builtinTemplate(templateID); ok {
+		return fmt.Errorf("invalid template: built-in templates cannot be deleted")
+	}
+
+	result, err := ts.collection.DeleteOne(context.Background(), bson.M{"_id": templateID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+// validateTemplate checks a template request and returns its fields cleaned up.
+func validateTemplate(req models.TemplateRequest) ([]models.TemplateField, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid template: name is required")
+	}
+	if len(name) > maxTemplateNameLen {
+		return nil, fmt.Errorf("invalid template: name must be at most %d characters", maxTemplateNameLen)
+	}
+	if len(req.Fields) == 0 || len(req.Fields) > maxTemplateFields {
+		return nil, fmt.Errorf("invalid template: between 1 and %d fields are required", maxTemplateFields)
+	}
+
+	fields := make([]models.TemplateField, len(req.Fields))
+	seen := make(map[string]bool)
+	for i, field := range req.Fields {
+		field.Label = strings.TrimSpace(field.Label)
+		field.Hint = strings.TrimSpace(field.Hint)
+		if !templateFieldKey.MatchString(field.Key) {
+			return nil, fmt.Errorf("invalid template: field key %q must be lowercase letters, digits and underscores", field.Key)
+		}
+		if seen[field.Key] {
+			return nil, fmt.Errorf("invalid template: duplicate field key %q", field.Key)
+		}
+		seen[field.Key] = true
+		if field.Label == "" {
+			return nil, fmt.Errorf("invalid template: field %q needs a label", field.Key)
+		}
+		if !templateFieldTypes[field.Type] {
+			return nil, fmt.Errorf("invalid template: field %q has unknown type %q", field.Key, field.Type)
+		}
+
+		switch field.Type {
+		case "scale":
+			if field.Min == 0 && field.Max == 0 {
+				field.Min, field.Max = 1, 10
+			}
+			if field.Min >= field.Max {
+				return nil, fmt.Errorf("invalid template: field %q needs min below max", field.Key)
+			}
+			field.Options = nil
+		case "choice":
+			var choices []string
+			for _, choice := range field.Options {
+				if choice = strings.TrimSpace(choice); choice != "" {
+					choices = append(choices, choice)
+				}
+			}
+			if len(choices) < 2 {
+				return nil, fmt.Errorf("invalid template: choice field %q needs at least two options", field.Key)
+			}
+			field.Options = choices
+			field.Min, field.Max = 0, 0
+		default:
+			field.Options = nil
+			field.Min, field.Max = 0, 0
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// fillEntry checks the prompt, template and answers of an entry request. Answers are
+// put in template order and written out as the entry's content; the title defaults to
+// the template's name.
+func (ts *TemplateService) fillEntry(userID string, req *models.CreateJournalRequest) error {
+	if req.PromptID != "" {
+		if _, ok := libraryPrompt(req.PromptID); !ok {
+			return fmt.Errorf("invalid entry: unknown prompt %q", req.PromptID)
+		}
+	}
+	if req.TemplateID == "" {
+		if len(req.Answers) > 0 {
+			return fmt.Errorf("invalid entry: answers need a template_id")
+		}
+		return nil
+	}
+
+	template, err := ts.GetTemplate(userID, req.TemplateID)
+	if err != nil {
+		if err.Error() == "template not found" {
+			return fmt.Errorf("invalid entry: unknown template %q", req.TemplateID)
+		}
+		return err
+	}
+	if len(req.Answers) == 0 {
+		return nil
+	}
+	if req.Envelope != nil {
+		return fmt.Errorf("invalid entry: answers must be sent inside the envelope")
+	}
+
+	answers, err := templateAnswers(template, req.Answers)
+	if err != nil {
+		return err
+	}
+	req.Answers = answers
+	req.Content = answersContent(template, answers)
+	if strings.TrimSpace(req.Title) == "" {
+		req.Title = template.Name
+	}
+	return nil
+}
+
+// templateAnswers validates answers against a template's fields and returns the
+// non-empty ones in field order.
+func templateAnswers(template *models.EntryTemplate, given []models.FieldAnswer) ([]models.FieldAnswer, error) {
+	values := make(map[string]string, len(given))
+	for _, answer := range given {
+		if _, ok := values[answer.Key]; ok {
+			return nil, fmt.Errorf("invalid entry: duplicate answer for %q", answer.Key)
+		}
+		values[answer.Key] = strings.TrimSpace(answer.Value)
+	}
+
+	var answers []models.FieldAnswer
+	for _, field := range template.Fields {
+		value, ok := values[field.Key]
+		delete(values, field.Key)
+		if !ok || value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("invalid entry: %q is required", field.Key)
+			}
+			continue
+		}
+
+		switch field.Type {
+		case "scale":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < field.Min || n > field.Max {
+				return nil, fmt.Errorf("invalid entry: %q must be a whole number from %d to %d", field.Key, field.Min, field.Max)
+			}
+			value = strconv.Itoa(n)
+		case "choice":
+			if !containsString(field.Options, value) {
+				return nil, fmt.Errorf("invalid entry: %q must be one of %s", field.Key, strings.Join(field.Options, ", "))
+			}
+		}
+		answers = append(answers, models.FieldAnswer{Key: field.Key, Value: value})
+	}
+	for key := range values {
+		return nil, fmt.Errorf("invalid entry: template %q has no field %q", template.ID, key)
+	}
+	return answers, nil
+}
+
+// answersContent writes answers out as entry text, one labelled section per field, so
+// that reflections, keywords and search work on templated entries as on any other.
+// A template with a single field, like morning pages, gives just the answer.
+func answersContent(template *models.EntryTemplate, answers []models.FieldAnswer) string {
+	if len(template.Fields) == 1 && len(answers) == 1 {
+		return answers[0].Value
+	}
+
+	fields := make(map[string]models.TemplateField, len(template.Fields))
+	for _, field := range template.Fields {
+		fields[field.Key] = field
+	}
+	sections := make([]string, len(answers))
+	for i, answer := range answers {
+		field := fields[answer.Key]
+		if field.Type == "scale" {
+			sections[i] = fmt.Sprintf("%s %s/%d", field.Label, answer.Value, field.Max)
+		} else {
+			sections[i] = field.Label + "\n" + answer.Value
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/encryption"
+	"soulprint-backend/keywords"
+	"soulprint-backend/models"
+	"soulprint-backend/topics"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// topicMatchThreshold is the share of entries (Jaccard similarity) a new cluster
+	// must have in common with an existing topic to continue it under the same ID.
+	topicMatchThreshold = 0.3
+	// topicHistoryLimit caps the size history kept per topic.
+	topicHistoryLimit = 365
+)
+
+type TopicService struct {
+	collection     *mongo.Collection
+	journalService *JournalService
+	encryptor      *encryption.Encryptor
+}
+
+func NewTopicService(client *mongo.Client, journalService *JournalService, encryptor *encryption.Encryptor) *TopicService {
+	return &TopicService{
+		collection:     client.Database(config.AppConfig.MongoDatabase).Collection("topics"),
+		journalService: journalService,
+		encryptor:      encryptor,
+	}
+}
+
+// EnsureIndexes creates the indexes used by topic queries.
+func (ts *TopicService) EnsureIndexes(ctx context.Context) error {
+	_, err := ts.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "size", Value: -1}},
+	})
+	return err
+}
+
+// GetTopics lists a user's topics, largest first. Retired topics are only included
+// when asked for.
+func (ts *TopicService) GetTopics(userID string, includeRetired bool) ([]models.Topic, error) {
+	filter := bson.M{"user_id": userID}
+	if !includeRetired {
+		filter["retired_at"] = nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "size", Value: -1}, {Key: "created_at", Value: 1}})
+
+	cursor, err := ts.collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find topics: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var result []models.Topic
+	if err = cursor.All(context.Background(), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode topics: %w", err)
+	}
+	for i := range result {
+		if err := openTopic(ts.encryptor, &result[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetTopicEntries returns the entries of a topic, newest first. Entries deleted since
+// the last refresh are left out.
+func (ts *TopicService) GetTopicEntries(userID, topicID string) ([]models.JournalEntry, error) {
+	objectID, err := primitive.ObjectIDFromHex(topicID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic ID: %w", err)
+	}
+
+	var topic models.Topic
+	opts := options.FindOne().SetProjection(bson.M{"entry_ids": 1})
+	err = ts.collection.FindOne(context.Background(), bson.M{"_id": objectID, "user_id": userID}, opts).Decode(&topic)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("topic not found")
+		}
+		return nil, fmt.Errorf("failed to find topic: %w", err)
+	}
+	if len(topic.EntryIDs) == 0 {
+		return []models.JournalEntry{}, nil
+	}
+	return ts.journalService.GetEntriesByIDs(userID, topic.EntryIDs)
+}
+
+// RefreshTopics clusters the user's entries again. Clusters that share most of their
+// entries with an existing topic continue it; the others start new topics, and
+// topics no cluster continues are retired. Every topic's history gets the new size.
+func (ts *TopicService) RefreshTopics(userID string) ([]models.Topic, error) {
+	entries, err := ts.journalService.GetEntries(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries = readableEntries(entries)
+
+	docs := make([]topics.Document, len(entries))
+	for i, entry := range entries {
+		docs[i] = topics.Document{Text: entryText(entry.Title, entry.Content), Language: entry.Language}
+		for _, keyword := range entry.Keywords {
+			docs[i].Keywords = append(docs[i].Keywords, keywords.Keyword{Term: keyword.Term, Score: keyword.Score})
+		}
+	}
+	clusters := topics.Cluster(docs, topics.Options{})
+
+	previous, err := ts.GetTopics(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entrySets := make([][]primitive.ObjectID, len(clusters))
+	for i, cluster := range clusters {
+		for _, member := range cluster.Members {
+			entrySets[i] = append(entrySets[i], entries[member].ID)
+		}
+	}
+	continued := matchTopics(previous, entrySets)
+
+	var refreshed []models.Topic
+	for i, cluster := range clusters {
+		topic := models.Topic{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			CreatedAt: now,
+		}
+		if j, ok := continued[i]; ok {
+			topic = previous[j]
+		}
+		topic.Label = cluster.Label
+		topic.Terms = cluster.Terms
+		topic.Size = len(cluster.Members)
+		topic.EntryIDs = entrySets[i]
+		topic.UpdatedAt = now
+		topic.History = appendTopicSize(topic.History, now, topic.Size)
+		if err := ts.save(&topic); err != nil {
+			return nil, err
+		}
+		refreshed = append(refreshed, topic)
+	}
+
+	matched := make(map[int]bool)
+	for _, j := range continued {
+		matched[j] = true
+	}
+	for j := range previous {
+		if matched[j] {
+			continue
+		}
+		topic := previous[j]
+		topic.Size = 0
+		topic.UpdatedAt = now
+		topic.RetiredAt = &now
+		topic.History = appendTopicSize(topic.History, now, 0)
+		if err := ts.save(&topic); err != nil {
+			return nil, err
+		}
+	}
+
+	if refreshed == nil {
+		refreshed = []models.Topic{}
+	}
+	return refreshed, nil
+}
+
+// RefreshAllTopics refreshes the topics of every user with entries and returns how
+// many users were refreshed. A failure for one user does not stop the others.
+func (ts *TopicService) RefreshAllTopics(ctx context.Context) (int, error) {
+	userIDs, err := ts.journalService.GetUserIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return refreshed, err
+		}
+		if _, err := ts.RefreshTopics(userID); err != nil {
+			log.Printf("topics: failed to refresh topics for %s: %v", userID, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+func (ts *TopicService) save(topic *models.Topic) error {
+	sealed, err := sealTopic(ts.encryptor, topic)
+	if err != nil {
+		return err
+	}
+	_, err = ts.collection.ReplaceOne(context.Background(), bson.M{"_id": topic.ID}, sealed, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save topic: %w", err)
+	}
+	return nil
+}
+
+// matchTopics pairs new clusters with the existing topics they continue, most similar
+// pairs first. It maps cluster index to topic index.
+func matchTopics(previous []models.Topic, entrySets [][]primitive.ObjectID) map[int]int {
+	type pair struct {
+		cluster, topic int
+		similarity     float64
+	}
+	var pairs []pair
+	for i, set := range entrySets {
+		for j, topic := range previous {
+			if similarity := jaccard(set, topic.EntryIDs); similarity >= topicMatchThreshold {
+				pairs = append(pairs, pair{i, j, similarity})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].similarity > pairs[b].similarity })
+
+	continued := make(map[int]int)
+	taken := make(map[int]bool)
+	for _, p := range pairs {
+		if _, ok := continued[p.cluster]; ok || taken[p.topic] {
+			continue
+		}
+		continued[p.cluster] = p.topic
+		taken[p.topic] = true
+	}
+	return continued
+}
+
+func jaccard(a, b []primitive.ObjectID) float64 {
+	set := make(map[primitive.ObjectID]bool, len(a))
+	for _, id := range a {
+		set[id] = true
+	}
+	shared := 0
+	for _, id := range b {
+		if set[id] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+func appendTopicSize(history []models.TopicSize, date time.Time, size int) []models.TopicSize {
+	history = append(history, models.TopicSize{Date: date, Size: size})
+	if len(history) > topicHistoryLimit {
+		history = history[len(history)-topicHistoryLimit:]
+	}
+	return history
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+	"soulprint-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ModelPrice is a model's price in USD per million tokens.
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// ModelPrices are list prices for OpenAI models. A model name is priced by its
+// longest matching prefix, so "gpt-4o-2024-08-06" uses "gpt-4o". AI_MODEL_PRICES adds
+// or overrides entries; unknown models and local models cost nothing.
+var ModelPrices = map[string]ModelPrice{
+	"gpt-3.5-turbo": {Prompt: 0.50, Completion: 1.50},
+	"gpt-4":         {Prompt: 30, Completion: 60},
+	"gpt-4-turbo":   {Prompt: 10, Completion: 30},
+	"gpt-4o":        {Prompt: 2.50, Completion: 10},
+	"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.60},
+}
+
+// BudgetExceededError is returned when a user has used up a token budget.
+type BudgetExceededError struct {
+	Period   string // "daily" or "monthly"
+	Limit    int
+	ResetsAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("usage budget exceeded: %s limit of %d tokens reached, resets at %s",
+		e.Period, e.Limit, e.ResetsAt.Format(time.RFC3339))
+}
+
+type UsageService struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	prices     map[string]ModelPrice
+}
+
+func NewUsageService(client *mongo.Client) *UsageService {
+	prices := make(map[string]ModelPrice, len(ModelPrices))
+	for model, price := range ModelPrices {
+		prices[model] = price
+	}
+	for _, item := range config.AppConfig.AIModelPrices {
+		model, price, err := parseModelPrice(item)
+		if err != nil {
+			log.Printf("Warning: ignoring AI_MODEL_PRICES entry %q: %v", item, err)
+			continue
+		}
+		prices[model] = price
+	}
+
+	return &UsageService{
+		client:     client,
+		collection: client.Database(config.AppConfig.MongoDatabase).Collection("ai_usage"),
+		prices:     prices,
+	}
+}
+
+// parseModelPrice parses "model=prompt/completion", prices in USD per million tokens.
+func parseModelPrice(item string) (string, ModelPrice, error) {
+	model, prices, ok := strings.Cut(item, "=")
+	promptPrice, completionPrice, ok2 := strings.Cut(prices, "/")
+	if !ok || !ok2 || strings.TrimSpace(model) == "" {
+		return "", ModelPrice{}, fmt.Errorf("expected model=prompt/completion")
+	}
+	var price ModelPrice
+	var err error
+	if price.Prompt, err = strconv.ParseFloat(strings.TrimSpace(promptPrice), 64); err != nil {
+		return "", ModelPrice{}, err
+	}
+	if price.Completion, err = strconv.ParseFloat(strings.TrimSpace(completionPrice), 64); err != nil {
+		return "", ModelPrice{}, err
+	}
+	return strings.TrimSpace(model), price, nil
+}
+
+// EnsureIndexes creates the indexes used by usage totals and reports.
+func (us *UsageService) EnsureIndexes(ctx context.Context) error {
+	_, err := us.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// Recorder returns a utils.UsageFunc that records calls on behalf of userID.
+func (us *UsageService) Recorder(userID string) utils.UsageFunc {
+	return func(usage utils.Usage) {
+		us.Record(userID, usage)
+	}
+}
+
+// Record stores one call's usage. Failures are logged rather than returned: the
+// call has already been made and paid for.
+func (us *UsageService) Record(userID string, usage utils.Usage) {
+	model := usage.Model
+	if model == "" {
+		model = config.AppConfig.OpenAIModel
+	}
+	cost := 0.0
+	if !usage.Local {
+		cost = us.cost(model, usage.PromptTokens, usage.CompletionTokens)
+	}
+
+	record := models.UsageRecord{
+		UserID:           userID,
+		Operation:        usage.Operation,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.PromptTokens + usage.CompletionTokens,
+		CostUSD:          cost,
+		CreatedAt:        time.Now(),
+	}
+	if _, err := us.collection.InsertOne(context.Background(), record); err != nil {
+		log.Printf("usage: failed to record %s call for %s: %v", usage.Operation, userID, err)
+	}
+}
+
+func (us *UsageService) cost(model string, promptTokens, completionTokens int) float64 {
+	best := ""
+	for prefix := range us.prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0
+	}
+	price := us.prices[best]
+	return (float64(promptTokens)*price.Prompt + float64(completionTokens)*price.Completion) / 1e6
+}
+
+// CheckBudget returns a *BudgetExceededError when the user has used up the daily or
+// monthly token budget. The check happens before a call, so the call that crosses
+// the limit still completes.
+func (us *UsageService) CheckBudget(userID string) error {
+	daily, monthly := config.AppConfig.AIDailyTokenBudget, config.AppConfig.AIMonthlyTokenBudget
+	if daily <= 0 && monthly <= 0 {
+		return nil
+	}
+
+	dayStart, monthStart := usagePeriods(time.Now())
+	if daily > 0 {
+		used, err := us.totals(bson.M{"user_id": userID, "created_at": bson.M{"$gte": dayStart}})
+		if err != nil {
+			return err
+		}
+		if used.TotalTokens >= daily {
+			return &BudgetExceededError{Period: "daily", Limit: daily, ResetsAt: dayStart.AddDate(0, 0, 1)}
+		}
+	}
+	if monthly > 0 {
+		used, err := us.totals(bson.M{"user_id": userID, "created_at": bson.M{"$gte": monthStart}})
+		if err != nil {
+			return err
+		}
+		if used.TotalTokens >= monthly {
+			return &BudgetExceededError{Period: "monthly", Limit: monthly, ResetsAt: monthStart.AddDate(0, 1, 0)}
+		}
+	}
+	return nil
+}
+
+// usagePeriods returns the start of the current UTC day and month, which is when
+// budgets reset.
+func usagePeriods(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (us *UsageService) GetUserUsage(userID string) (*models.UserUsage, error) {
+	dayStart, monthStart := usagePeriods(time.Now())
+	monthFilter := bson.M{"user_id": userID, "created_at": bson.M{"$gte": monthStart}}
+
+	today, err := us.totals(bson.M{"user_id": userID, "created_at": bson.M{"$gte": dayStart}})
+	if err != nil {
+		return nil, err
+	}
+	month, err := us.totals(monthFilter)
+	if err != nil {
+		return nil, err
+	}
+	byOperation, err := us.groups(monthFilter, "$operation")
+	if err != nil {
+		return nil, err
+	}
+	byModel, err := us.groups(monthFilter, "$model")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserUsage{
+		UserID:        userID,
+		Today:         today,
+		Month:         month,
+		DailyBudget:   usageBudget(config.AppConfig.AIDailyTokenBudget, today.TotalTokens, dayStart.AddDate(0, 0, 1)),
+		MonthlyBudget: usageBudget(config.AppConfig.AIMonthlyTokenBudget, month.TotalTokens, monthStart.AddDate(0, 1, 0)),
+		ByOperation:   byOperation,
+		ByModel:       byModel,
+	}, nil
+}
+
+func usageBudget(limit, used int, resetsAt time.Time) models.UsageBudget {
+	budget := models.UsageBudget{Limit: limit, Used: used, ResetsAt: resetsAt}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		budget.Remaining = &remaining
+	}
+	return budget
+}
+
+// GetGlobalUsage reports usage across all users in [from, to).
+func (us *UsageService) GetGlobalUsage(from, to time.Time) (*models.GlobalUsage, error) {
+	filter := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+
+	totals, err := us.totals(filter)
+	if err != nil {
+		return nil, err
+	}
+	byUser, err := us.groups(filter, "$user_id")
+	if err != nil {
+		return nil, err
+	}
+	byModel, err := us.groups(filter, "$model")
+	if err != nil {
+		return nil, err
+	}
+	byDay, err := us.groups(filter, bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(byDay, func(i, j int) bool { return byDay[i].Key < byDay[j].Key })
+
+	return &models.GlobalUsage{
+		From:    from,
+		To:      to,
+		Totals:  totals,
+		ByUser:  byUser,
+		ByModel: byModel,
+		ByDay:   byDay,
+	}, nil
+}
+
+func (us *UsageService) totals(filter bson.M) (models.UsageTotals, error) {
+	groups, err := us.groups(filter, nil)
+	if err != nil || len(groups) == 0 {
+		return models.UsageTotals{}, err
+	}
+	return groups[0].UsageTotals, nil
+}
+
+// groups sums usage matching filter per value of key, largest token count first.
+func (us *UsageService) groups(filter bson.M, key interface{}) ([]models.UsageGroup, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               key,
+			"calls":             bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$total_tokens"},
+			"cost_usd":          bson.M{"$sum": "$cost_usd"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total_tokens", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := us.collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	groups := []models.UsageGroup{}
+	if err = cursor.All(context.Background(), &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,587 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/encryption"
+	"soulprint-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Webhook event names.
+const (
+	EventEntryCreated      = "entry.created"
+	EventEntryUpdated      = "entry.updated"
+	EventEntryDeleted      = "entry.deleted"
+	EventReflectionCreated = "reflection.created"
+	EventPing              = "ping"
+)
+
+var webhookEvents = []string{EventEntryCreated, EventEntryUpdated, EventEntryDeleted, EventReflectionCreated}
+
+type WebhookService struct {
+	client        *mongo.Client
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+	httpClient    *http.Client
+	encryptor     *encryption.Encryptor
+}
+
+// NewWebhookService creates the service. Delivery payloads carry entry and reflection
+// content, so they are encrypted at rest with encryptor.
+func NewWebhookService(client *mongo.Client, encryptor *encryption.Encryptor) *WebhookService {
+	db := client.Database(config.AppConfig.MongoDatabase)
+	return &WebhookService{
+		client:        client,
+		subscriptions: db.Collection("webhook_subscriptions"),
+		deliveries:    db.Collection("webhook_deliveries"),
+		httpClient:    NewWebhookHTTPClient(),
+		encryptor:     encryptor,
+	}
+}
+
+func (ws *WebhookService) CreateSubscription(userID string, req models.WebhookRequest) (*models.WebhookSubscription, error) {
+	if err := validateWebhookRequest(req); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	subscription := &models.WebhookSubscription{
+		UserID:      userID,
+		URL:         req.URL,
+		Secret:      "whsec_" + hex.EncodeToString(secret),
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result, err := ws.subscriptions.InsertOne(context.Background(), subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	subscription.ID = result.InsertedID.(primitive.ObjectID)
+	return subscription, nil
+}
+
+func (ws *WebhookService) GetSubscriptions(userID string) ([]models.WebhookSubscription, error) {
+	cursor, err := ws.subscriptions.Find(context.Background(), bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhooks: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var subscriptions []models.WebhookSubscription
+	if err = cursor.All(context.Background(), &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to decode webhooks: %w", err)
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (ws *WebhookService) UpdateSubscription(userID, subscriptionID string, req models.WebhookRequest) (*models.WebhookSubscription, error) {
+	if err := validateWebhookRequest(req); err != nil {
+		return nil, err
+	}
+
+	subscription, err := ws.getSubscription(userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"url":         req.URL,
+		"events":      req.Events,
+		"description": req.Description,
+		"active":      req.Active == nil || *req.Active,
+		"updated_at":  time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = ws.subscriptions.FindOneAndUpdate(context.Background(), bson.M{"_id": subscription.ID, "user_id": userID}, update, opts).Decode(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+func (ws *WebhookService) DeleteSubscription(userID, subscriptionID string) error {
+	subscription, err := ws.getSubscription(userID, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ws.subscriptions.DeleteOne(context.Background(), bson.M{"_id": subscription.ID}); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if _, err := ws.deliveries.DeleteMany(context.Background(), bson.M{"subscription_id": subscription.ID}); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the delivery log of a subscription, newest first.
+func (ws *WebhookService) GetDeliveries(userID, subscriptionID string, limit int64) ([]models.WebhookDelivery, error) {
+	subscription, err := ws.getSubscription(userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := ws.deliveries.Find(context.Background(), bson.M{"subscription_id": subscription.ID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook deliveries: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var deliveries []models.WebhookDelivery
+	if err = cursor.All(context.Background(), &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+	for i := range deliveries {
+		if err := openFields(ws.encryptor, deliveries[i].UserID, &deliveries[i].Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return deliveries, nil
+}
+
+// Ping sends a test event to the subscription once, synchronously, and returns the
+// logged delivery.
+func (ws *WebhookService) Ping(userID, subscriptionID string) (*models.WebhookDelivery, error) {
+	subscription, err := ws.getSubscription(userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := ws.newDelivery(subscription, EventPing, map[string]interface{}{
+		"subscription_id": subscription.ID.Hex(),
+		"message":         "Webhook test from Soulprint",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ws.attempt(subscription, delivery, 1)
+	return delivery, nil
+}
+
+// Publish fans an event out to the user's matching subscriptions. Deliveries are
+// logged immediately and sent in the background with retries. It is safe to call on
+// a nil service.
+func (ws *WebhookService) Publish(userID, event string, data interface{}) {
+	if ws == nil {
+		return
+	}
+
+	filter := bson.M{
+		"user_id": userID,
+		"active":  true,
+		"events":  bson.M{"$in": []string{event, "*"}},
+	}
+	cursor, err := ws.subscriptions.Find(context.Background(), filter)
+	if err != nil {
+		log.Printf("webhooks: failed to find subscriptions for %s: %v", event, err)
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var subscriptions []models.WebhookSubscription
+	if err = cursor.All(context.Background(), &subscriptions); err != nil {
+		log.Printf("webhooks: failed to decode subscriptions for %s: %v", event, err)
+		return
+	}
+
+	for i := range subscriptions {
+		subscription := subscriptions[i]
+		delivery, err := ws.newDelivery(&subscription, event, data)
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+			continue
+		}
+		go ws.deliver(&subscription, delivery)
+	}
+}
+
+// ResumeStaleDeliveries retries pending deliveries whose retry loop was interrupted,
+// e.g. by a restart. It returns how many deliveries were resumed.
+func (ws *WebhookService) ResumeStaleDeliveries(ctx context.Context) (int, error) {
+	filter := bson.M{
+		"status":          "pending",
+		"next_attempt_at": bson.M{"$lt": time.Now().Add(-10 * time.Minute)},
+	}
+	cursor, err := ws.deliveries.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find pending deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []models.WebhookDelivery
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return 0, fmt.Errorf("failed to decode pending deliveries: %w", err)
+	}
+
+	resumed := 0
+	for i := range deliveries {
+		delivery := deliveries[i]
+		if err := openFields(ws.encryptor, delivery.UserID, &delivery.Payload); err != nil {
+			log.Printf("webhooks: skipping delivery %s: %v", delivery.ID.Hex(), err)
+			continue
+		}
+		var subscription models.WebhookSubscription
+		err := ws.subscriptions.FindOne(ctx, bson.M{"_id": delivery.SubscriptionID}).Decode(&subscription)
+		if err != nil {
+			ws.finish(&delivery, "failed", "subscription no longer exists")
+			continue
+		}
+		go ws.deliver(&subscription, &delivery)
+		resumed++
+	}
+	return resumed, nil
+}
+
+func (ws *WebhookService) newDelivery(subscription *models.WebhookSubscription, event string, data interface{}) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:             primitive.NewObjectID(),
+		SubscriptionID: subscription.ID,
+		UserID:         subscription.UserID,
+		Event:          event,
+		EntryIDs:       payloadEntryIDs(data),
+		Status:         "pending",
+		NextAttemptAt:  &now,
+		CreatedAt:      now,
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         delivery.ID.Hex(),
+		"event":      event,
+		"created_at": now,
+		"data":       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	delivery.Payload = string(payload)
+
+	stored, err := ws.sealDelivery(delivery)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ws.deliveries.InsertOne(context.Background(), stored); err != nil {
+		return nil, fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// payloadEntryIDs returns the IDs of the entries an event's data is about.
+func payloadEntryIDs(data interface{}) []primitive.ObjectID {
+	switch data := data.(type) {
+	case *models.JournalEntry:
+		return []primitive.ObjectID{data.ID}
+	case *models.Reflection:
+		if !data.EntryID.IsZero() {
+			return []primitive.ObjectID{data.EntryID}
+		}
+		return data.EntryIDs
+	case map[string]interface{}:
+		if id, ok := data["id"].(string); ok {
+			if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+				return []primitive.ObjectID{objectID}
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteEntryDeliveries deletes the logged deliveries that carry any of the entries,
+// for when they are purged.
+func (ws *WebhookService) DeleteEntryDeliveries(entryIDs []primitive.ObjectID) error {
+	if ws == nil {
+		return nil
+	}
+	if _, err := ws.deliveries.DeleteMany(context.Background(), bson.M{"entry_ids": bson.M{"$in": entryIDs}}); err != nil {
+		return fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// deliver retries with exponential backoff until the endpoint accepts the event or
+// the attempts run out.
+func (ws *WebhookService) deliver(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	maxAttempts := config.AppConfig.WebhookMaxAttempts
+	backoff := config.AppConfig.WebhookRetryBackoff
+
+	for delivery.Attempts < maxAttempts {
+		if ws.attempt(subscription, delivery, maxAttempts) {
+			return
+		}
+		if delivery.Status != "pending" {
+			return
+		}
+		time.Sleep(backoff << uint(delivery.Attempts-1))
+	}
+}
+
+// attempt makes one delivery attempt and records the outcome. It returns true when
+// the endpoint accepted the event.
+func (ws *WebhookService) attempt(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, maxAttempts int) bool {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	status, err := ws.post(subscription, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		delivery.DeliveredAt = &now
+		ws.finish(delivery, "succeeded", "")
+		return true
+	}
+
+	if delivery.Attempts >= maxAttempts {
+		ws.finish(delivery, "failed", err.Error())
+		return false
+	}
+
+	next := now.Add(config.AppConfig.WebhookRetryBackoff << uint(delivery.Attempts-1))
+	delivery.NextAttemptAt = &next
+	delivery.Error = err.Error()
+	ws.save(delivery)
+	return false
+}
+
+func (ws *WebhookService) post(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Soulprint-Webhooks/1.0")
+	req.Header.Set("X-Soulprint-Event", delivery.Event)
+	req.Header.Set("X-Soulprint-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Soulprint-Timestamp", timestamp)
+	req.Header.Set("X-Soulprint-Signature", "sha256="+SignWebhookPayload(subscription.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := ws.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload computes the hex HMAC-SHA256 of "<timestamp>.<body>" that
+// receivers compare against the X-Soulprint-Signature header.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ws *WebhookService) finish(delivery *models.WebhookDelivery, status, errMsg string) {
+	delivery.Status = status
+	delivery.Error = errMsg
+	delivery.NextAttemptAt = nil
+	ws.save(delivery)
+}
+
+func (ws *WebhookService) save(delivery *models.WebhookDelivery) {
+	stored, err := ws.sealDelivery(delivery)
+	if err == nil {
+		_, err = ws.deliveries.ReplaceOne(context.Background(), bson.M{"_id": delivery.ID}, stored)
+	}
+	if err != nil {
+		log.Printf("webhooks: failed to update delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+// sealDelivery returns a copy of the delivery with its payload encrypted; deliveries
+// in memory keep the plaintext payload that is sent and signed.
+func (ws *WebhookService) sealDelivery(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	sealed := *delivery
+	if err := sealFields(ws.encryptor, delivery.UserID, &sealed.Payload); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+func (ws *WebhookService) getSubscription(userID, subscriptionID string) (*models.WebhookSubscription, error) {
+	objectID, err := primitive.ObjectIDFromHex(subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook ID: %w", err)
+	}
+
+	var subscription models.WebhookSubscription
+	err = ws.subscriptions.FindOne(context.Background(), bson.M{"_id": objectID, "user_id": userID}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// EnsureIndexes creates the indexes used by subscription lookups and delivery logs.
+func (ws *WebhookService) EnsureIndexes(ctx context.Context) error {
+	if _, err := ws.subscriptions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "active", Value: 1}},
+	}); err != nil {
+		return err
+	}
+	_, err := ws.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "entry_ids", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	return err
+}
+
+func validateWebhookRequest(req models.WebhookRequest) error {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return err
+	}
+	if len(req.Events) == 0 {
+		return fmt.Errorf("invalid events: at least one event is required")
+	}
+	for _, event := range req.Events {
+		if event == "*" {
+			continue
+		}
+		known := false
+		for _, candidate := range webhookEvents {
+			if event == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("invalid event %q, expected one of %v or \"*\"", event, webhookEvents)
+		}
+	}
+	return nil
+}
+
+// validateWebhookURL accepts http and https URLs whose host resolves to public
+// addresses only. Reminder webhook targets are checked the same way.
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	return checkWebhookHost(parsed.Hostname())
+}
+
+// blockedWebhookNets are address ranges webhooks may not reach on top of loopback,
+// private, link-local and multicast addresses: shared address space (which holds some
+// cloud metadata services), "this network", IETF protocol assignments and benchmarking.
+var blockedWebhookNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "64:ff9b::/96"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// blockedWebhookIP reports whether a webhook must not be sent to ip, because it points
+// into the server's own network: loopback, private and link-local addresses, which
+// include the 169.254.169.254 metadata endpoint, and the ranges above.
+func blockedWebhookIP(ip net.IP) bool {
+	if config.AppConfig.WebhookAllowPrivate {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range blockedWebhookNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebhookHost resolves a webhook host and rejects it if any of its addresses is
+// blocked. Deliveries check again when dialing, since DNS can change in between.
+func checkWebhookHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("invalid webhook URL: cannot resolve %q", host)
+	}
+	for _, addr := range addrs {
+		if blockedWebhookIP(addr.IP) {
+			return fmt.Errorf("invalid webhook URL: %q resolves to a private or reserved address", host)
+		}
+	}
+	return nil
+}
+
+// NewWebhookHTTPClient returns the client webhook deliveries and reminder webhooks are
+// sent with. Every connection,
+// including those made for redirects, is checked against the address it actually
+// dials, so a host that resolves elsewhere after subscribing cannot reach the internal
+// network. Proxies are not used, as they would hide the target address.
+func NewWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedWebhookIP(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: config.AppConfig.WebhookTimeout, Transport: transport}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"soulprint-backend/config"
+	"soulprint-backend/models"
+)
+
+func TestValidateWebhookRequestRejectsInternalHosts(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://localhost/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://192.168.0.10/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://100.100.100.200/", false},
+		{"http://0.0.0.0/", false},
+		{"http://[fd00:ec2::254]/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+		{"https://93.184.216.34/hook", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := validateWebhookRequest(models.WebhookRequest{URL: tt.url, Events: []string{"*"}})
+			if (err == nil) != tt.allowed {
+				t.Errorf("err = %v, want allowed = %v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	if _, err := NewWebhookHTTPClient().Get(receiver.URL); err == nil {
+		t.Fatal("delivery to a loopback receiver succeeded, want it refused")
+	}
+
+	config.AppConfig.WebhookAllowPrivate = true
+	resp, err := NewWebhookHTTPClient().Get(receiver.URL)
+	if err != nil {
+		t.Fatalf("with WebhookAllowPrivate: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,373 @@
+// Package topics groups journal entries by theme. Entries become TF-IDF vectors over
+// their content words, which are clustered with spherical k-means (cosine similarity).
+// The number of topics is picked by silhouette score unless fixed, and, in the spirit
+// of density-based clustering, entries that fit no topic well are left out rather
+// than forced into one. Topics are named after the key phrases their entries share.
+package topics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"soulprint-backend/keywords"
+)
+
+// Document is an entry to cluster.
+type Document struct {
+	Text     string
+	Language string
+	Keywords []keywords.Keyword // the entry's key phrases, used to name topics
+}
+
+// Options tune Cluster. The zero value picks the number of topics itself.
+type Options struct {
+	K             int     // number of topics; 0 picks between 2 and MaxK
+	MaxK          int     // defaults to 12
+	MinSize       int     // topics with fewer entries are dropped; defaults to 2
+	MinSimilarity float64 // entries less similar to their topic are left out; defaults to 0.05
+	Terms         int     // top terms per topic; defaults to 5
+}
+
+// Topic is a cluster of documents.
+type Topic struct {
+	Label   string
+	Terms   []string // the most characteristic words, best first
+	Members []int    // indexes into the documents, most central first
+}
+
+// maxIterations bounds each k-means run.
+const maxIterations = 50
+
+// Cluster groups documents into topics, largest first. It is deterministic: the same
+// documents always give the same topics.
+func Cluster(docs []Document, opts Options) []Topic {
+	if opts.MaxK <= 0 {
+		opts.MaxK = 12
+	}
+	if opts.MinSize <= 0 {
+		opts.MinSize = 2
+	}
+	if opts.MinSimilarity <= 0 {
+		opts.MinSimilarity = 0.05
+	}
+	if opts.Terms <= 0 {
+		opts.Terms = 5
+	}
+
+	space := vectorize(docs)
+	if len(space.points) < 2*opts.MinSize {
+		return nil
+	}
+
+	var best *clustering
+	if opts.K > 0 {
+		best = kmeans(space, min(opts.K, len(space.points)))
+	} else {
+		bestScore := math.Inf(-1)
+		for k := 2; k <= opts.MaxK && k*opts.MinSize <= len(space.points); k++ {
+			candidate := kmeans(space, k)
+			if score := candidate.silhouette(); score > bestScore {
+				best, bestScore = candidate, score
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	members := make([][]int, len(best.centroids))
+	for i, cluster := range best.assignment {
+		if best.similarity[i] >= opts.MinSimilarity {
+			members[cluster] = append(members[cluster], i)
+		}
+	}
+
+	var topics []Topic
+	for cluster, points := range members {
+		if len(points) < opts.MinSize {
+			continue
+		}
+		sort.SliceStable(points, func(a, b int) bool {
+			return best.similarity[points[a]] > best.similarity[points[b]]
+		})
+		topic := Topic{Terms: space.topTerms(best.centroids[cluster], opts.Terms)}
+		for _, point := range points {
+			topic.Members = append(topic.Members, space.points[point].doc)
+		}
+		topic.Label = label(docs, topic)
+		topics = append(topics, topic)
+	}
+	sort.SliceStable(topics, func(i, j int) bool {
+		return len(topics[i].Members) > len(topics[j].Members)
+	})
+	return topics
+}
+
+// label names a topic after the key phrase that occurs in the most of its entries,
+// or after its top terms when no phrase recurs.
+func label(docs []Document, topic Topic) string {
+	texts := make([]string, len(topic.Members))
+	weight := make(map[string]float64)
+	for i, member := range topic.Members {
+		texts[i] = " " + strings.Join(keywords.Words(docs[member].Text, docs[member].Language), " ") + " "
+		for _, keyword := range docs[member].Keywords {
+			weight[keyword.Term] += keyword.Score
+		}
+	}
+
+	best, bestSupport := "", 1
+	for term, w := range weight {
+		support := 0
+		for _, text := range texts {
+			if strings.Contains(text, " "+term+" ") {
+				support++
+			}
+		}
+		better := support > bestSupport ||
+			support == bestSupport && best != "" && (w > weight[best] || w == weight[best] && term < best)
+		if better {
+			best, bestSupport = term, support
+		}
+	}
+	if best != "" {
+		return best
+	}
+	terms := topic.Terms
+	if len(terms) > 2 {
+		terms = terms[:2]
+	}
+	return strings.Join(terms, " & ")
+}
+
+type weight struct {
+	term  int
+	value float64
+}
+
+// point is a document's unit-length sparse TF-IDF vector.
+type point struct {
+	doc     int
+	weights []weight
+}
+
+type space struct {
+	vocabulary []string
+	points     []point
+}
+
+// vectorize builds TF-IDF vectors. Words used in only one entry cannot link entries
+// and words in most of them do not tell them apart, so both are left out.
+func vectorize(docs []Document) space {
+	counts := make([]map[string]int, len(docs))
+	df := make(map[string]int)
+	for i, doc := range docs {
+		counts[i] = make(map[string]int)
+		for _, word := range keywords.Words(doc.Text, doc.Language) {
+			if counts[i][word] == 0 {
+				df[word]++
+			}
+			counts[i][word]++
+		}
+	}
+
+	n := len(docs)
+	var vocabulary []string
+	for word, f := range df {
+		if f >= 2 && (n < 10 || float64(f) <= 0.5*float64(n)) {
+			vocabulary = append(vocabulary, word)
+		}
+	}
+	sort.Strings(vocabulary)
+	index := make(map[string]int, len(vocabulary))
+	for i, word := range vocabulary {
+		index[word] = i
+	}
+
+	s := space{vocabulary: vocabulary}
+	for i := range docs {
+		var weights []weight
+		norm := 0.0
+		for word, tf := range counts[i] {
+			term, ok := index[word]
+			if !ok {
+				continue
+			}
+			idf := 1 + math.Log(float64(1+n)/float64(1+df[word]))
+			value := (1 + math.Log(float64(tf))) * idf
+			weights = append(weights, weight{term: term, value: value})
+			norm += value * value
+		}
+		if len(weights) == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for j := range weights {
+			weights[j].value /= norm
+		}
+		sort.Slice(weights, func(a, b int) bool { return weights[a].term < weights[b].term })
+		s.points = append(s.points, point{doc: i, weights: weights})
+	}
+	return s
+}
+
+func (s space) topTerms(centroid []float64, limit int) []string {
+	order := make([]int, len(centroid))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return centroid[order[a]] > centroid[order[b]] })
+	var terms []string
+	for _, term := range order {
+		if len(terms) == limit || centroid[term] <= 0 {
+			break
+		}
+		terms = append(terms, s.vocabulary[term])
+	}
+	return terms
+}
+
+func dot(p point, centroid []float64) float64 {
+	sum := 0.0
+	for _, w := range p.weights {
+		sum += w.value * centroid[w.term]
+	}
+	return sum
+}
+
+type clustering struct {
+	space      space
+	centroids  [][]float64
+	assignment []int     // cluster of each point
+	similarity []float64 // cosine similarity of each point to its centroid
+}
+
+// kmeans runs spherical k-means seeded with k-means++ from a fixed seed.
+func kmeans(s space, k int) *clustering {
+	rng := rand.New(rand.NewSource(1))
+	c := &clustering{
+		space:      s,
+		assignment: make([]int, len(s.points)),
+		similarity: make([]float64, len(s.points)),
+	}
+
+	c.centroids = append(c.centroids, c.centroidOf([]int{rng.Intn(len(s.points))}))
+	for len(c.centroids) < k {
+		// Pick the next seed with probability proportional to its squared distance
+		// from the nearest seed so far.
+		distances := make([]float64, len(s.points))
+		total := 0.0
+		for i, p := range s.points {
+			nearest := 0.0
+			for _, centroid := range c.centroids {
+				nearest = math.Max(nearest, dot(p, centroid))
+			}
+			distances[i] = (1 - nearest) * (1 - nearest)
+			total += distances[i]
+		}
+		next := 0
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, d := range distances {
+				if target -= d; target <= 0 {
+					next = i
+					break
+				}
+			}
+		} else {
+			next = rng.Intn(len(s.points))
+		}
+		c.centroids = append(c.centroids, c.centroidOf([]int{next}))
+	}
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := c.assign()
+		if !changed && iteration > 0 {
+			break
+		}
+		members := make([][]int, k)
+		for i, cluster := range c.assignment {
+			members[cluster] = append(members[cluster], i)
+		}
+		for cluster := range c.centroids {
+			if len(members[cluster]) == 0 {
+				// Re-seed an empty cluster with the point that fits its own worst.
+				members[cluster] = []int{c.worstFit()}
+			}
+			c.centroids[cluster] = c.centroidOf(members[cluster])
+		}
+	}
+	c.assign()
+	return c
+}
+
+// assign moves every point to its most similar centroid and reports whether any moved.
+func (c *clustering) assign() bool {
+	changed := false
+	for i, p := range c.space.points {
+		best, bestSimilarity := 0, math.Inf(-1)
+		for cluster, centroid := range c.centroids {
+			if similarity := dot(p, centroid); similarity > bestSimilarity {
+				best, bestSimilarity = cluster, similarity
+			}
+		}
+		if c.assignment[i] != best {
+			changed = true
+		}
+		c.assignment[i] = best
+		c.similarity[i] = bestSimilarity
+	}
+	return changed
+}
+
+func (c *clustering) worstFit() int {
+	worst := 0
+	for i := range c.similarity {
+		if c.similarity[i] < c.similarity[worst] {
+			worst = i
+		}
+	}
+	c.similarity[worst] = math.Inf(1)
+	return worst
+}
+
+// centroidOf returns the unit-length mean of the given points.
+func (c *clustering) centroidOf(points []int) []float64 {
+	centroid := make([]float64, len(c.space.vocabulary))
+	for _, i := range points {
+		for _, w := range c.space.points[i].weights {
+			centroid[w.term] += w.value
+		}
+	}
+	norm := 0.0
+	for _, v := range centroid {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range centroid {
+			centroid[i] /= norm
+		}
+	}
+	return centroid
+}
+
+// silhouette is the simplified silhouette score in cosine distance: for each point,
+// how much closer it is to its own centroid than to the next best one, averaged.
+func (c *clustering) silhouette() float64 {
+	total := 0.0
+	for i, p := range c.space.points {
+		own := 1 - c.similarity[i]
+		other := math.Inf(1)
+		for cluster, centroid := range c.centroids {
+			if cluster != c.assignment[i] {
+				other = math.Min(other, 1-dot(p, centroid))
+			}
+		}
+		if denominator := math.Max(own, other); denominator > 0 {
+			total += (other - own) / denominator
+		}
+	}
+	return total / float64(len(c.space.points))
+}
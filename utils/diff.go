@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxDiffEdits bounds the edit distance myers searches for. The trace it keeps grows with
+// the square of the distance, so heavily rewritten texts are diffed line by line instead,
+// and texts that differ even more are shown as replaced outright.
+const maxDiffEdits = 1000
+
+// DiffOp is one run of a word-level diff.
+type DiffOp struct {
+	Op   string `json:"op"` // "equal", "insert", "delete"
+	Text string `json:"text"`
+}
+
+// DiffWords computes a word-level diff between two texts. Whitespace is kept as its own
+// tokens so that joining the "equal" and "insert" runs reproduces the new text.
+func DiffWords(from, to string) []DiffOp {
+	a := tokenize(from)
+	b := tokenize(to)
+
+	// Trim the common prefix and suffix first; most edits touch a small part of an entry.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []DiffOp
+	ops = appendOp(ops, "equal", a[:prefix])
+	for _, op := range diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		ops = appendOp(ops, op.Op, []string{op.Text})
+	}
+	ops = appendOp(ops, "equal", a[len(a)-suffix:])
+	return ops
+}
+
+// diffMiddle diffs the changed part of two texts by word, falling back to lines and then
+// to a plain replacement when they are too far apart.
+func diffMiddle(a, b []string) []DiffOp {
+	if ops, ok := myers(a, b, maxDiffEdits); ok {
+		return ops
+	}
+	from, to := strings.Join(a, ""), strings.Join(b, "")
+	if ops, ok := myers(splitLines(from), splitLines(to), maxDiffEdits); ok {
+		return ops
+	}
+	return []DiffOp{{Op: "delete", Text: from}, {Op: "insert", Text: to}}
+}
+
+// DiffStats counts the words added and removed by a diff.
+func DiffStats(ops []DiffOp) (added, removed int) {
+	for _, op := range ops {
+		switch op.Op {
+		case "insert":
+			added += len(strings.Fields(op.Text))
+		case "delete":
+			removed += len(strings.Fields(op.Text))
+		}
+	}
+	return added, removed
+}
+
+// splitLines splits text into lines that keep their newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func tokenize(text string) []string {
+	var tokens []string
+	start := 0
+	inSpace := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > start && space != inSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+func appendOp(ops []DiffOp, op string, tokens []string) []DiffOp {
+	if len(tokens) == 0 {
+		return ops
+	}
+	text := strings.Join(tokens, "")
+	if n := len(ops); n > 0 && ops[n-1].Op == op {
+		ops[n-1].Text += text
+		return ops
+	}
+	return append(ops, DiffOp{Op: op, Text: text})
+}
+
+// myers implements the O((N+M)D) greedy diff algorithm and returns one op per token.
+// It gives up once the edit distance exceeds maxEdits. Only the diagonals -d..d of each
+// step are kept for the backtrack, so memory stays within O(maxEdits²).
+func myers(a, b []string, maxEdits int) ([]DiffOp, bool) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrack walks the trace back from the end; trace[d] holds diagonals -d..d.
+func backtrack(a, b []string, trace [][]int, depth int) []DiffOp {
+	var reversed []DiffOp
+	x, y := len(a), len(b)
+
+	for d := depth; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, DiffOp{Op: "equal", Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, DiffOp{Op: "insert", Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, DiffOp{Op: "delete", Text: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, DiffOp{Op: "equal", Text: a[x]})
+	}
+
+	ops := make([]DiffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"soulprint-backend/config"
+	"soulprint-backend/promptguard"
+	"soulprint-backend/safety"
+)
+
+// ErrFakeFailure is returned by the fake provider for injected failures.
+var ErrFakeFailure = errors.New("fake provider: injected failure")
+
+// FakeOptions configures the fake provider.
+type FakeOptions struct {
+	// Latency is added to every call.
+	Latency time.Duration
+	// ErrorRate is the fraction of calls that fail. Which calls fail is decided by
+	// hashing the prompt, so the same input always succeeds or always fails.
+	ErrorRate float64
+	// FailOperations always fail, e.g. "keywords" or "digest".
+	FailOperations []string
+}
+
+// FakeOptionsFromConfig reads the FAKE_LLM_* settings.
+func FakeOptionsFromConfig() FakeOptions {
+	return FakeOptions{
+		Latency:        config.AppConfig.FakeLLMLatency,
+		ErrorRate:      config.AppConfig.FakeLLMErrorRate,
+		FailOperations: config.AppConfig.FakeLLMFailOperations,
+	}
+}
+
+// FakeModel is a deterministic stand-in for a language model. It answers from
+// templates filled in with words taken from the journal text, so the same prompt
+// always gets the same answer. It is selected with AI_PROVIDER=fake for demos and
+// tests, and never makes a network call.
+type FakeModel struct {
+	name string
+	opts FakeOptions
+}
+
+func NewFakeModel(name string, opts FakeOptions) *FakeModel {
+	if name == "" {
+		name = "fake"
+	}
+	return &FakeModel{name: name, opts: opts}
+}
+
+func (fm *FakeModel) Name() string {
+	return fm.name
+}
+
+// Complete answers one prompt. The operation is one of the Usage operations.
+func (fm *FakeModel) Complete(operation, systemPrompt, userPrompt string) (string, error) {
+	if fm.opts.Latency > 0 {
+		time.Sleep(fm.opts.Latency)
+	}
+	if err := fm.injectedFailure(operation, userPrompt); err != nil {
+		return "", err
+	}
+	return fm.respond(operation, systemPrompt, userPrompt), nil
+}
+
+// Usage estimates token counts as word counts.
+func (fm *FakeModel) Usage(systemPrompt, userPrompt, response string) (promptTokens, completionTokens int) {
+	return len(strings.Fields(systemPrompt)) + len(strings.Fields(userPrompt)), len(strings.Fields(response))
+}
+
+func (fm *FakeModel) injectedFailure(operation, userPrompt string) error {
+	for _, failing := range fm.opts.FailOperations {
+		if failing == operation {
+			return fmt.Errorf("%w (%s)", ErrFakeFailure, operation)
+		}
+	}
+	if fm.opts.ErrorRate > 0 && float64(fakeHash(operation+userPrompt)%1000)/1000 < fm.opts.ErrorRate {
+		return fmt.Errorf("%w (%s)", ErrFakeFailure, operation)
+	}
+	return nil
+}
+
+func (fm *FakeModel) respond(operation, systemPrompt, userPrompt string) string {
+	text := strings.Join(fakeEntries(userPrompt), "\n")
+	keywords := fakeKeywords(text, 5)
+	seed := fakeHash(text)
+
+	switch operation {
+	case "keywords":
+		return strings.Join(keywords, ", ")
+	case "safety":
+		assessment, _ := safety.NewLexiconClassifier().Classify(text)
+		reply, _ := json.Marshal(map[string]interface{}{"level": assessment.Level.String(), "categories": append([]string{}, assessment.Categories...)})
+		return string(reply)
+	case "judge":
+		return fmt.Sprintf(`{"score": %d, "reason": "Deterministic score from the fake provider."}`, 3+seed%3)
+	case "summary", "digest":
+		return fmt.Sprintf("Across these %d entries, the recurring themes were %s. %s",
+			len(fakeEntries(userPrompt)), fakeList(keywords), fakePick(fakeClosings, seed))
+	}
+
+	// Reflections: the task wording tells the types apart, the system prompt the length.
+	var b strings.Builder
+	switch {
+	case strings.Contains(userPrompt, "concise summary"):
+		fmt.Fprintf(&b, "This entry is mostly about %s.", fakeList(keywords))
+	case strings.Contains(userPrompt, "thoughtful analysis"):
+		fmt.Fprintf(&b, "A pattern runs through this entry: %s keep coming up together. ", fakeList(keywords))
+		b.WriteString(fakePick(fakeQuestions, seed))
+	default:
+		fmt.Fprintf(&b, fakePick(fakeOpenings, seed), fakeList(keywords))
+		b.WriteString(" ")
+		b.WriteString(fakePick(fakeQuestions, seed+1))
+	}
+	if length, ok := ReflectionLengths["long"]; ok && strings.Contains(systemPrompt, length.Instruction) {
+		b.WriteString(" ")
+		b.WriteString(fakePick(fakeClosings, seed))
+	}
+	return b.String()
+}
+
+var fakeOpenings = []string{
+	"It sounds like %s took up a lot of space today.",
+	"Reading this, %s stand out as what mattered most.",
+	"There is a lot here about %s.",
+}
+
+var fakeQuestions = []string{
+	"What would you like to remember about this moment a month from now?",
+	"What is one small thing that would make tomorrow a little easier?",
+	"Which part of this surprised you the most?",
+}
+
+var fakeClosings = []string{
+	"Noticing these threads is already a step towards understanding them.",
+	"Be as patient with yourself as you would be with a friend.",
+	"Writing it down gives you something to come back to.",
+}
+
+// fakeEntries returns the unescaped text of every delimited entry in a prompt, or
+// the whole prompt if it has none. Delimit puts the opening tag on its own line,
+// which tells it apart from the tag's mention in the system prompt.
+func fakeEntries(prompt string) []string {
+	open, close := "<"+promptguard.EntryTag+">\n", "\n</"+promptguard.EntryTag+">"
+	var entries []string
+	for {
+		start := strings.Index(prompt, open)
+		if start < 0 {
+			break
+		}
+		prompt = prompt[start+len(open):]
+		end := strings.Index(prompt, close)
+		if end < 0 {
+			break
+		}
+		entries = append(entries, html.UnescapeString(strings.TrimSpace(prompt[:end])))
+		prompt = prompt[end+len(close):]
+	}
+	if len(entries) == 0 {
+		entries = []string{prompt}
+	}
+	return entries
+}
+
+var fakeStopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "also": true, "because": true, "been": true,
+	"before": true, "being": true, "could": true, "didn't": true, "don't": true, "every": true,
+	"feel": true, "from": true, "have": true, "into": true, "just": true, "know": true,
+	"like": true, "more": true, "much": true, "only": true, "really": true, "should": true,
+	"some": true, "that": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "thing": true, "things": true, "this": true, "today": true,
+	"very": true, "want": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "while": true, "with": true, "would": true, "your": true,
+}
+
+// fakeKeywords returns the most frequent longer words of a text, ties broken
+// alphabetically.
+func fakeKeywords(text string, limit int) []string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r == '\'' || r > 127)
+	}) {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) >= 4 && !fakeStopwords[word] {
+			counts[word]++
+		}
+	}
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > limit {
+		words = words[:limit]
+	}
+	if len(words) == 0 {
+		words = []string{"reflection"}
+	}
+	return words
+}
+
+func fakeList(words []string) string {
+	if len(words) > 3 {
+		words = words[:3]
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+func fakePick(options []string, seed uint32) string {
+	return options[seed%uint32(len(options))]
+}
+
+func fakeHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
@@ -0,0 +1,130 @@
+package utils_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"soulprint-backend/config"
+	"soulprint-backend/fakellm"
+	"soulprint-backend/utils"
+)
+
+const testEntry = "The garden was full of tomatoes. I spent the evening in the garden watering the tomatoes."
+
+// newTestClient starts a fakellm server with the given rules and returns a client for
+// provider ("openai" or "local") pointed at it, and the server for assertions.
+func newTestClient(t *testing.T, provider string, rules ...fakellm.Rule) (*utils.OpenAIClient, *fakellm.Server) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	server := fakellm.New(fakellm.Options{Rules: rules})
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	url := srv.URL
+	if provider == "openai" {
+		url += "/v1"
+	}
+	client, err := utils.NewClient(utils.Provider{Name: provider, Model: "test-model", URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestProviders(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     fakellm.Request
+		local    bool
+	}{
+		{"openai", fakellm.Request{Provider: "openai", Endpoint: "chat", Model: "test-model"}, false},
+		{"local", fakellm.Request{Provider: "ollama", Endpoint: "generate", Model: "test-model"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			client, server := newTestClient(t, tt.provider)
+			var usage []utils.Usage
+			client = client.WithUsage(func(u utils.Usage) { usage = append(usage, u) })
+
+			reflection, err := client.GenerateReflection(testEntry, "insight", utils.ReflectionStyle{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(reflection, "garden") {
+				t.Errorf("reflection = %q, want it to mention the garden", reflection)
+			}
+
+			requests := server.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests))
+			}
+			got := requests[0]
+			if got.Provider != tt.want.Provider || got.Endpoint != tt.want.Endpoint || got.Model != tt.want.Model {
+				t.Errorf("request = %s %s %s, want %s %s %s", got.Provider, got.Endpoint, got.Model, tt.want.Provider, tt.want.Endpoint, tt.want.Model)
+			}
+			if !strings.Contains(got.Prompt, testEntry) {
+				t.Errorf("prompt does not contain the entry: %q", got.Prompt)
+			}
+
+			if len(usage) != 1 || usage[0].Operation != "reflection" || usage[0].Local != tt.local || usage[0].CompletionTokens == 0 {
+				t.Errorf("usage = %+v", usage)
+			}
+		})
+	}
+}
+
+func TestProvidersScriptedResponse(t *testing.T) {
+	for _, provider := range []string{"openai", "local"} {
+		t.Run(provider, func(t *testing.T) {
+			client, _ := newTestClient(t, provider, fakellm.Rule{Name: "keywords", Match: "key themes", Response: "garden, tomatoes, evening"})
+			keywords, err := client.ExtractKeywords(testEntry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(keywords, "|") != "garden|tomatoes|evening" {
+				t.Errorf("keywords = %q", keywords)
+			}
+		})
+	}
+}
+
+func TestProvidersFaults(t *testing.T) {
+	faults := []fakellm.Rule{
+		{Name: "server error", Status: 500},
+		{Name: "rate limited", Status: 429, Error: "slow down"},
+		{Name: "malformed body", Malformed: true},
+		{Name: "dropped connection", Disconnect: true},
+	}
+	for _, provider := range []string{"openai", "local"} {
+		for _, fault := range faults {
+			t.Run(provider+"/"+fault.Name, func(t *testing.T) {
+				client, server := newTestClient(t, provider, fault)
+				reflection, err := client.GenerateReflection(testEntry, "insight", utils.ReflectionStyle{})
+				if err == nil {
+					t.Fatalf("got reflection %q, want an error", reflection)
+				}
+				if requests := server.Requests(); len(requests) == 0 || requests[0].Rule != fault.Name {
+					t.Errorf("fault rule %q did not answer: %+v", fault.Name, requests)
+				}
+			})
+		}
+	}
+}
+
+func TestProvidersRecoverAfterFault(t *testing.T) {
+	for _, provider := range []string{"openai", "local"} {
+		t.Run(provider, func(t *testing.T) {
+			client, _ := newTestClient(t, provider, fakellm.Rule{Name: "one failure", Status: 503, Times: 1})
+			if _, err := client.GenerateReflection(testEntry, "insight", utils.ReflectionStyle{}); err == nil {
+				t.Fatal("first call succeeded, want the injected failure")
+			}
+			if _, err := client.GenerateReflection(testEntry, "insight", utils.ReflectionStyle{}); err != nil {
+				t.Fatalf("second call: %v", err)
+			}
+		})
+	}
+}